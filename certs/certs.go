@@ -0,0 +1,185 @@
+// Package certs configures TLS and reloads certificates when they change.
+//
+// sn - https://github.com/sn
+package certs
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sn/service/config"
+)
+
+// versions are the TLS versions by configuration name
+var versions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// Reloader serves a certificate and key pair, reloading it when the files
+// change. Connections established with a previous certificate are unaffected.
+type Reloader struct {
+	certFile string
+	keyFile  string
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	modTime time.Time
+
+	stop     chan struct{}
+	stopOnce *sync.Once
+	done     chan struct{}
+}
+
+// NewReloader loads a certificate and key pair
+func NewReloader(certFile, keyFile string) (*Reloader, error) {
+	r := &Reloader{certFile: certFile, keyFile: keyFile}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload loads the certificate and key pair again. The current certificate is
+// kept if loading fails.
+func (r *Reloader) Reload() error {
+	modTime, err := r.filesModTime()
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("Unable to load certificate: %v", err)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cert = &cert
+	r.modTime = modTime
+	return nil
+}
+
+// filesModTime returns the latest modification time of the certificate and
+// key files
+func (r *Reloader) filesModTime() (time.Time, error) {
+	var latest time.Time
+	for _, name := range []string{r.certFile, r.keyFile} {
+		fi, err := os.Stat(name)
+		if err != nil {
+			return latest, err
+		}
+		if fi.ModTime().After(latest) {
+			latest = fi.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+// GetCertificate returns the current certificate, for tls.Config
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// Watch reloads the certificate every interval when its files have changed,
+// and whenever a value is received on reload, such as SIGHUP, until stopped.
+// Failures are logged and the current certificate is kept.
+func (r *Reloader) Watch(interval time.Duration, reload <-chan os.Signal) {
+	r.stop = make(chan struct{})
+	r.stopOnce = &sync.Once{}
+	r.done = make(chan struct{})
+	go func() {
+		defer close(r.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				modTime, err := r.filesModTime()
+				r.mu.RLock()
+				changed := err == nil && !modTime.Equal(r.modTime)
+				r.mu.RUnlock()
+				if !changed {
+					continue
+				}
+			case <-reload:
+			case <-r.stop:
+				return
+			}
+			if err := r.Reload(); err != nil {
+				slog.Error("Unable to reload certificate", "cert_file", r.certFile, "error", err)
+				continue
+			}
+			slog.Info("Reloaded certificate", "cert_file", r.certFile)
+		}
+	}()
+}
+
+// Stop stops watching, waiting until the context is done. Stop can be called
+// more than once.
+func (r *Reloader) Stop(ctx context.Context) error {
+	if r.stop == nil {
+		return nil
+	}
+	r.stopOnce.Do(func() { close(r.stop) })
+	select {
+	case <-r.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// NewConfig returns the TLS configuration of a server using the reloader's
+// certificate
+func NewConfig(c config.TLS, r *Reloader) (*tls.Config, error) {
+	cfg := &tls.Config{GetCertificate: r.GetCertificate}
+
+	version, ok := versions[c.MinVersion]
+	if !ok {
+		return nil, fmt.Errorf("Unknown TLS version %q.", c.MinVersion)
+	}
+	cfg.MinVersion = version
+
+	if len(c.CipherSuites) > 0 {
+		ids := map[string]uint16{}
+		for _, suite := range tls.CipherSuites() {
+			ids[suite.Name] = suite.ID
+		}
+		for _, name := range c.CipherSuites {
+			id, ok := ids[strings.TrimSpace(name)]
+			if !ok {
+				return nil, fmt.Errorf("Unknown or insecure cipher suite %q.", name)
+			}
+			cfg.CipherSuites = append(cfg.CipherSuites, id)
+		}
+	}
+
+	switch c.ClientAuth {
+	case config.ClientAuthOptional:
+		cfg.ClientAuth = tls.VerifyClientCertIfGiven
+	case config.ClientAuthRequire:
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	if c.ClientCAFile != "" {
+		pem, err := ioutil.ReadFile(c.ClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.ClientCAs = x509.NewCertPool()
+		if !cfg.ClientCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("No certificates found in %s.", c.ClientCAFile)
+		}
+	}
+	return cfg, nil
+}
@@ -0,0 +1,247 @@
+// Package certs configures TLS and reloads certificates when they change.
+//
+// sn - https://github.com/sn
+package certs
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/sn/service/config"
+)
+
+// writeCert writes a certificate and key for the common name, signed by the
+// parent, and returns them. A nil parent makes a self-signed CA.
+func writeCert(t *testing.T, certFile, keyFile, name string, parent *tls.Certificate) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	signer, signerKey := template, interface{}(key)
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature
+	} else {
+		signer, signerKey = parent.Leaf, parent.PrivateKey
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	if err := ioutil.WriteFile(certFile, certPEM, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(keyFile, keyPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert.Leaf, err = x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+// commonName returns the common name of the reloader's current certificate
+func commonName(t *testing.T, r *Reloader) string {
+	cert, err := r.GetCertificate(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return leaf.Subject.CommonName
+}
+
+func TestReloader(t *testing.T) {
+	dir, err := ioutil.TempDir("", "certs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+
+	if _, err := NewReloader(certFile, keyFile); err == nil {
+		t.Error("Expected missing files to fail.")
+	}
+
+	writeCert(t, certFile, keyFile, "first", nil)
+	r, err := NewReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name := commonName(t, r); name != "first" {
+		t.Errorf("Expected first certificate, got %s.", name)
+	}
+
+	// A failed reload keeps the current certificate
+	if err := ioutil.WriteFile(keyFile, []byte("garbage"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Reload(); err == nil {
+		t.Error("Expected invalid key to fail.")
+	}
+	if name := commonName(t, r); name != "first" {
+		t.Errorf("Expected first certificate to be kept, got %s.", name)
+	}
+
+	// Changed files are reloaded when polled
+	r.Watch(10*time.Millisecond, nil)
+	writeCert(t, certFile, keyFile, "second", nil)
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(certFile, future, future)
+	deadline := time.Now().Add(2 * time.Second)
+	for commonName(t, r) != "second" && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if err := r.Stop(context.Background()); err != nil {
+		t.Error(err)
+	}
+	if name := commonName(t, r); name != "second" {
+		t.Errorf("Expected changed certificate to be reloaded, got %s.", name)
+	}
+
+	// Signals reload regardless of modification times
+	hup := make(chan os.Signal, 1)
+	r.Watch(time.Hour, hup)
+	writeCert(t, certFile, keyFile, "third", nil)
+	hup <- syscall.SIGHUP
+	deadline = time.Now().Add(2 * time.Second)
+	for commonName(t, r) != "third" && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if err := r.Stop(context.Background()); err != nil {
+		t.Error(err)
+	}
+	if err := r.Stop(context.Background()); err != nil {
+		t.Error(err)
+	}
+	if name := commonName(t, r); name != "third" {
+		t.Errorf("Expected signal to reload certificate, got %s.", name)
+	}
+}
+
+func TestNewConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "certs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	writeCert(t, certFile, keyFile, "localhost", nil)
+	r, err := NewReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := config.Default().Server.TLS
+	c.MinVersion = "1.3"
+	c.CipherSuites = []string{"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256"}
+	cfg, err := NewConfig(c, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.MinVersion != tls.VersionTLS13 || len(cfg.CipherSuites) != 1 || cfg.ClientAuth != tls.NoClientCert {
+		t.Error("Expected configured version, cipher suites, and client auth.")
+	}
+
+	bad := []config.TLS{
+		{MinVersion: "2.0"},
+		{MinVersion: "1.2", CipherSuites: []string{"TLS_RSA_WITH_RC4_128_SHA"}},
+		{MinVersion: "1.2", ClientCAFile: filepath.Join(dir, "missing.pem")},
+		{MinVersion: "1.2", ClientCAFile: keyFile},
+	}
+	for _, c := range bad {
+		if _, err := NewConfig(c, r); err == nil {
+			t.Errorf("%+v: expected error.", c)
+		}
+	}
+}
+
+func TestClientAuth(t *testing.T) {
+	dir, err := ioutil.TempDir("", "certs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	caFile := filepath.Join(dir, "ca.pem")
+	ca := writeCert(t, caFile, filepath.Join(dir, "ca-key.pem"), "ca", nil)
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	writeCert(t, certFile, keyFile, "localhost", &ca)
+	client := writeCert(t, filepath.Join(dir, "client.pem"), filepath.Join(dir, "client-key.pem"), "internal", &ca)
+
+	r, err := NewReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := config.Default().Server.TLS
+	c.ClientCAFile = caFile
+	c.ClientAuth = config.ClientAuthRequire
+	cfg, err := NewConfig(c, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	srv.TLS = cfg
+	srv.StartTLS()
+	defer srv.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.Leaf)
+	get := func(certs ...tls.Certificate) (string, error) {
+		tr := &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots, ServerName: "localhost", Certificates: certs}}
+		defer tr.CloseIdleConnections()
+		resp, err := (&http.Client{Transport: tr}).Get(srv.URL)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		return string(body), err
+	}
+
+	if _, err := get(); err == nil {
+		t.Error("Expected request without a client certificate to fail.")
+	}
+	if name, err := get(client); err != nil || name != "internal" {
+		t.Errorf("Expected client certificate to be verified, got %q, %v.", name, err)
+	}
+}
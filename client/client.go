@@ -0,0 +1,286 @@
+// Package client is a typed Go client for the API of the service.
+//
+// sn - https://github.com/sn
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sn/service/types"
+)
+
+// Version is the API version the client calls
+const Version = "/v1"
+
+// Client calls the API of a service. It keeps the session token of the last
+// Login, and logs in again with the same credentials when the session
+// expires. A Client is safe for concurrent use.
+type Client struct {
+	// HTTPClient makes the requests, or http.DefaultClient if nil
+	HTTPClient *http.Client
+
+	// Retries is how many times a request is retried after a network error
+	// or a 429, 502, 503 or 504 response. Requests other than GET are sent
+	// with an Idempotency-Key, so a retry replays the response of an
+	// attempt the service completed instead of repeating it.
+	Retries int
+
+	// Backoff is how long the first retry waits, doubling for each retry
+	// up to MaxBackoff, unless the response has a Retry-After header
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+
+	baseURL *url.URL
+
+	mu       sync.Mutex
+	token    string
+	id       types.UUID
+	password string
+
+	// refreshing serializes logging in again, so concurrent requests with
+	// an expired token start a single session
+	refreshing sync.Mutex
+}
+
+// New returns a client of the service at a base URL, such as
+// https://sn.example.com
+func New(baseURL string) (*Client, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid base URL: %v", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("Base URL must be an http or https URL.")
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + Version
+	return &Client{
+		Retries:    3,
+		Backoff:    100 * time.Millisecond,
+		MaxBackoff: 5 * time.Second,
+		baseURL:    u,
+	}, nil
+}
+
+// Error is an error response of the API. Errors with the same status code
+// match with errors.Is, so errors.Is(err, client.ErrNotFound) reports whether
+// the response was 404.
+type Error struct {
+	StatusCode int
+	Message    string
+	RequestID  string
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("%d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// Is reports whether an error is one of the errors below with the same
+// status code
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Message == "" && t.StatusCode == e.StatusCode
+}
+
+// Errors of the status codes the API responds with
+var (
+	ErrBadRequest           = &Error{StatusCode: http.StatusBadRequest}
+	ErrUnauthorized         = &Error{StatusCode: http.StatusUnauthorized}
+	ErrForbidden            = &Error{StatusCode: http.StatusForbidden}
+	ErrNotFound             = &Error{StatusCode: http.StatusNotFound}
+	ErrConflict             = &Error{StatusCode: http.StatusConflict}
+	ErrPreconditionFailed   = &Error{StatusCode: http.StatusPreconditionFailed}
+	ErrUnsupportedMediaType = &Error{StatusCode: http.StatusUnsupportedMediaType}
+	ErrUnprocessableEntity  = &Error{StatusCode: http.StatusUnprocessableEntity}
+	ErrTooManyRequests      = &Error{StatusCode: http.StatusTooManyRequests}
+)
+
+// request is a request to the API
+type request struct {
+	method string
+	// path is escaped, with its segments escaped by url.PathEscape
+	path        string
+	query       url.Values
+	body        interface{}
+	contentType string
+	// auth sends the session token, logging in again if it has expired
+	auth bool
+}
+
+// do makes a request, retrying it if it fails transiently, and decodes the
+// JSON response into out, or the plain text response if out is a *string. It
+// returns the headers of the response.
+func (c *Client) do(ctx context.Context, req *request, out interface{}) (http.Header, error) {
+	var body []byte
+	if req.body != nil {
+		var err error
+		if body, err = json.Marshal(req.body); err != nil {
+			return nil, err
+		}
+	}
+	var idempotencyKey string
+	if req.method != "GET" {
+		key, err := types.NewV4()
+		if err != nil {
+			return nil, err
+		}
+		idempotencyKey = string(key)
+	}
+
+	refreshed := false
+	for attempt := 0; ; attempt++ {
+		token := c.Token()
+		resp, err := c.send(ctx, req, body, token, idempotencyKey)
+		if err != nil {
+			if ctx.Err() != nil || attempt >= c.Retries {
+				return nil, err
+			}
+			if err := c.wait(ctx, attempt, 0); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if resp.StatusCode == http.StatusUnauthorized && req.auth && token != "" && !refreshed {
+			apiErr := responseError(resp)
+			resp.Body.Close()
+			refreshed = true
+			retry, err := c.refresh(ctx, token)
+			if err != nil {
+				return nil, err
+			}
+			if !retry {
+				return resp.Header, apiErr
+			}
+			attempt--
+			continue
+		}
+
+		if resp.StatusCode >= 400 {
+			apiErr := responseError(resp)
+			resp.Body.Close()
+			if !retryable(resp.StatusCode) || attempt >= c.Retries {
+				return resp.Header, apiErr
+			}
+			if err := c.wait(ctx, attempt, apiErr.RetryAfter); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		defer resp.Body.Close()
+		if out == nil || resp.StatusCode == http.StatusNoContent {
+			return resp.Header, nil
+		}
+		if s, ok := out.(*string); ok {
+			b, err := ioutil.ReadAll(resp.Body)
+			*s = string(b)
+			return resp.Header, err
+		}
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return resp.Header, fmt.Errorf("Unable to decode response: %v", err)
+		}
+		return resp.Header, nil
+	}
+}
+
+// send makes one attempt at a request
+func (c *Client) send(ctx context.Context, req *request, body []byte, token, idempotencyKey string) (*http.Response, error) {
+	path, err := url.PathUnescape(req.path)
+	if err != nil {
+		return nil, err
+	}
+	u := *c.baseURL
+	u.RawPath = u.EscapedPath() + req.path
+	u.Path += path
+	u.RawQuery = req.query.Encode()
+	var r io.Reader
+	if body != nil {
+		r = bytes.NewReader(body)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, u.String(), r)
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Accept", "application/json")
+	if body != nil {
+		contentType := req.contentType
+		if contentType == "" {
+			contentType = "application/json"
+		}
+		httpReq.Header.Set("Content-Type", contentType)
+	}
+	if req.auth && token != "" {
+		httpReq.Header.Set("Authorization", token)
+	}
+	if idempotencyKey != "" {
+		httpReq.Header.Set("Idempotency-Key", idempotencyKey)
+	}
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return httpClient.Do(httpReq)
+}
+
+// wait waits before a retry, for the Retry-After of the response if it has
+// one, or else an exponential backoff with jitter
+func (c *Client) wait(ctx context.Context, attempt int, retryAfter time.Duration) error {
+	delay := retryAfter
+	if delay <= 0 {
+		delay = c.Backoff << uint(attempt)
+		if delay > c.MaxBackoff && c.MaxBackoff > 0 {
+			delay = c.MaxBackoff
+		}
+		// between half and all of the backoff, so clients retrying at
+		// once spread out
+		delay = delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+	}
+	t := time.NewTimer(delay)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// retryable reports whether a request failing with a status code may
+// succeed if retried
+func retryable(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// responseError returns the error of an error response
+func responseError(resp *http.Response) *Error {
+	b, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 4096))
+	e := &Error{
+		StatusCode: resp.StatusCode,
+		Message:    strings.TrimSpace(string(b)),
+		RequestID:  resp.Header.Get("X-Request-ID"),
+	}
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		e.RetryAfter = time.Duration(seconds) * time.Second
+	}
+	return e
+}
@@ -0,0 +1,136 @@
+// Package client is a typed Go client for the API of the service.
+//
+// sn - https://github.com/sn
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sn/service/router"
+)
+
+var api http.Handler
+
+// newClient returns a client of a test server serving the API through a
+// handler wrapping it
+func newClient(t *testing.T, wrap func(http.Handler) http.Handler) *Client {
+	h := api
+	if wrap != nil {
+		h = wrap(api)
+	}
+	server := httptest.NewServer(h)
+	t.Cleanup(server.Close)
+	c, err := New(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Backoff = time.Millisecond
+	return c
+}
+
+// failing fails the first n requests with a status
+func failing(n int32, status int, requests *int32) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(requests, 1) <= n {
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(status)
+				return
+			}
+			h.ServeHTTP(w, r)
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	for _, baseURL := range []string{"", "ftp://example.com", "http://[::1"} {
+		if _, err := New(baseURL); err == nil {
+			t.Errorf("%q: expected error.", baseURL)
+		}
+	}
+	c, err := New("https://example.com/api/")
+	if err != nil || c.baseURL.String() != "https://example.com/api/v1" {
+		t.Errorf("Expected the versioned base URL, got %v, %v.", c.baseURL, err)
+	}
+}
+
+func TestError(t *testing.T) {
+	c := newClient(t, nil)
+	_, err := c.User(context.Background(), "3ddb2e4c-1d0c-4d4f-8b47-7d5dbe1e6c0d")
+	var apiErr *Error
+	if !errors.Is(err, ErrNotFound) || !errors.As(err, &apiErr) {
+		t.Fatalf("Expected ErrNotFound, got %v.", err)
+	}
+	if apiErr.Message != "Not Found" || apiErr.RequestID == "" || err.Error() != "404 Not Found: Not Found" {
+		t.Errorf("Expected the message and request ID of the response, got %+v.", apiErr)
+	}
+	if errors.Is(err, ErrBadRequest) {
+		t.Error("Expected errors of other status codes not to match.")
+	}
+}
+
+func TestRetry(t *testing.T) {
+	var requests int32
+	c := newClient(t, failing(2, http.StatusServiceUnavailable, &requests))
+	if _, err := c.Users(context.Background(), ListOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if requests != 3 {
+		t.Errorf("Expected 3 attempts, got %d.", requests)
+	}
+
+	requests = 0
+	c.Retries = 1
+	if _, err := c.Users(context.Background(), ListOptions{}); !errors.Is(err, &Error{StatusCode: http.StatusServiceUnavailable}) {
+		t.Errorf("Expected 503 after the retries, got %v.", err)
+	}
+
+	requests = 0
+	c = newClient(t, failing(1, http.StatusBadRequest, &requests))
+	if _, err := c.Users(context.Background(), ListOptions{}); !errors.Is(err, ErrBadRequest) || requests != 1 {
+		t.Errorf("Expected 400 without retries, got %v after %d attempts.", err, requests)
+	}
+}
+
+func TestRetryReplays(t *testing.T) {
+	// the first attempt is handled, but its response is lost
+	var requests int32
+	c := newClient(t, func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&requests, 1) == 1 {
+				h.ServeHTTP(httptest.NewRecorder(), r)
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			h.ServeHTTP(w, r)
+		})
+	})
+	u, err := c.CreateUser(context.Background(), UserInput{Username: "riley", Password: "1@E4s67890", Email: "riley@example.com"})
+	if err != nil || u.Username != "riley" {
+		t.Errorf("Expected the retry to replay the created user, got %v, %v.", u, err)
+	}
+}
+
+func TestContext(t *testing.T) {
+	var requests int32
+	c := newClient(t, failing(100, http.StatusServiceUnavailable, &requests))
+	c.Backoff = time.Hour
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := c.Users(ctx, ListOptions{}); err != context.DeadlineExceeded {
+		t.Errorf("Expected the deadline to stop the retries, got %v.", err)
+	}
+}
+
+func TestMain(m *testing.M) {
+	router.RateLimits.Requests = 0
+	api = router.NewRouter()
+	os.Exit(m.Run())
+}
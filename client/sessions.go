@@ -0,0 +1,115 @@
+// Package client is a typed Go client for the API of the service.
+//
+// sn - https://github.com/sn
+package client
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"time"
+
+	"github.com/sn/service/types"
+)
+
+// Token returns the session token, or "" if the client is not logged in
+func (c *Client) Token() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.token
+}
+
+// SetToken sets the session token, from an earlier Login. A client with a
+// token it did not log in for cannot log in again when the session expires.
+func (c *Client) SetToken(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token, c.id, c.password = token, "", ""
+}
+
+// Login starts a session as a user, and keeps the credentials to start
+// another when the session expires
+func (c *Client) Login(ctx context.Context, id types.UUID, password string) error {
+	token, err := c.login(ctx, id, password)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token, c.id, c.password = token, id, password
+	return nil
+}
+
+// login starts a session and returns its token
+func (c *Client) login(ctx context.Context, id types.UUID, password string) (string, error) {
+	var token string
+	_, err := c.do(ctx, &request{
+		method: "POST",
+		path:   "/auth",
+		body:   map[string]interface{}{"ID": id, "Password": password},
+	}, &token)
+	return token, err
+}
+
+// Logout ends the session, and forgets the credentials of the client
+func (c *Client) Logout(ctx context.Context) error {
+	_, err := c.do(ctx, &request{method: "DELETE", path: "/auth", auth: true}, nil)
+	if err != nil && !errors.Is(err, ErrUnauthorized) {
+		return err
+	}
+	c.SetToken("")
+	return nil
+}
+
+// refresh logs in again if the token that was refused is still the current
+// one, reporting whether the request should be retried with a new token
+func (c *Client) refresh(ctx context.Context, refused string) (bool, error) {
+	c.refreshing.Lock()
+	defer c.refreshing.Unlock()
+	c.mu.Lock()
+	token, id, password := c.token, c.id, c.password
+	c.mu.Unlock()
+	if token != refused {
+		return true, nil
+	}
+	if id == "" {
+		return false, nil
+	}
+	token, err := c.login(ctx, id, password)
+	if err != nil {
+		return false, err
+	}
+	c.mu.Lock()
+	if c.id == id {
+		c.token = token
+	}
+	c.mu.Unlock()
+	return true, nil
+}
+
+// Session is a session of a user. Its ID identifies the session to revoke
+// it, and is not its token.
+type Session struct {
+	ID      types.UUID
+	UserID  types.UUID
+	Expires time.Time
+}
+
+// Sessions lists the unexpired sessions of the logged in user. Admins see
+// every session, or those of the user with an ID, if it is not empty.
+func (c *Client) Sessions(ctx context.Context, userID types.UUID) ([]Session, error) {
+	query := url.Values{}
+	if userID != "" {
+		query.Set("user_id", string(userID))
+	}
+	var sessions []Session
+	_, err := c.do(ctx, &request{method: "GET", path: "/sessions", query: query, auth: true}, &sessions)
+	return sessions, err
+}
+
+// RevokeSession ends a session of the logged in user, or for admins, of any
+// user
+func (c *Client) RevokeSession(ctx context.Context, id types.UUID) error {
+	_, err := c.do(ctx, &request{method: "DELETE", path: "/sessions/" + url.PathEscape(string(id)), auth: true}, nil)
+	return err
+}
@@ -0,0 +1,77 @@
+// Package client is a typed Go client for the API of the service.
+//
+// sn - https://github.com/sn
+package client
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sn/service/session"
+)
+
+func TestLogin(t *testing.T) {
+	c := newClient(t, nil)
+	ctx := context.Background()
+	u, err := c.CreateUser(ctx, UserInput{Username: "sage", Password: "1@E4s67890", Email: "sage@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Login(ctx, u.ID, "wrong"); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Expected ErrUnauthorized, got %v.", err)
+	}
+	if err := c.Login(ctx, "3ddb2e4c-1d0c-4d4f-8b47-7d5dbe1e6c0d", "1@E4s67890"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v.", err)
+	}
+	if _, err := c.Sessions(ctx, ""); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Expected ErrUnauthorized before logging in, got %v.", err)
+	}
+
+	if err := c.Login(ctx, u.ID, "1@E4s67890"); err != nil || c.Token() == "" {
+		t.Fatalf("Expected a token, got %v.", err)
+	}
+	sessions, err := c.Sessions(ctx, "")
+	if err != nil || len(sessions) != 1 || sessions[0].UserID != u.ID {
+		t.Fatalf("Expected the session, got %v, %v.", sessions, err)
+	}
+
+	if err := c.Logout(ctx); err != nil || c.Token() != "" {
+		t.Errorf("Expected to log out, got %v.", err)
+	}
+	if s := session.GetPublic(sessions[0].ID); s.ID != "" {
+		t.Error("Expected the session to be ended.")
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	c := newClient(t, nil)
+	ctx := context.Background()
+	u, err := c.CreateUser(ctx, UserInput{Username: "tatum", Password: "1@E4s67890", Email: "tatum@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Login(ctx, u.ID, "1@E4s67890"); err != nil {
+		t.Fatal(err)
+	}
+	expired := c.Token()
+	session.Remove(session.Find(ctx, expired).ID)
+
+	sessions, err := c.Sessions(ctx, "")
+	if err != nil || len(sessions) != 1 {
+		t.Fatalf("Expected a new session, got %v, %v.", sessions, err)
+	}
+	if c.Token() == expired {
+		t.Error("Expected the token to be replaced.")
+	}
+	if err := c.RevokeSession(ctx, sessions[0].ID); err != nil {
+		t.Fatal(err)
+	}
+
+	// a token set by hand cannot be refreshed
+	c.SetToken(expired)
+	if _, err := c.Sessions(ctx, ""); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Expected ErrUnauthorized, got %v.", err)
+	}
+}
@@ -0,0 +1,52 @@
+// Package client is a typed Go client for the API of the service.
+//
+// sn - https://github.com/sn
+package client
+
+import (
+	"context"
+	"net/url"
+)
+
+// ReservedUsernames lists the reserved usernames. Admins only.
+func (c *Client) ReservedUsernames(ctx context.Context) ([]string, error) {
+	var names []string
+	_, err := c.do(ctx, &request{method: "GET", path: "/usernames/reserved", auth: true}, &names)
+	return names, err
+}
+
+// ReserveUsername reserves a username, and the usernames confusable with it.
+// Admins only.
+func (c *Client) ReserveUsername(ctx context.Context, name string) error {
+	_, err := c.do(ctx, &request{method: "PUT", path: "/usernames/reserved/" + url.PathEscape(name), auth: true}, nil)
+	return err
+}
+
+// UnreserveUsername removes a username from the reserved usernames. Admins
+// only.
+func (c *Client) UnreserveUsername(ctx context.Context, name string) error {
+	_, err := c.do(ctx, &request{method: "DELETE", path: "/usernames/reserved/" + url.PathEscape(name), auth: true}, nil)
+	return err
+}
+
+// BannedPatterns lists the banned username patterns. Admins only.
+func (c *Client) BannedPatterns(ctx context.Context) ([]string, error) {
+	var patterns []string
+	_, err := c.do(ctx, &request{method: "GET", path: "/usernames/banned", auth: true}, &patterns)
+	return patterns, err
+}
+
+// BanPattern bans the usernames matching a regular expression, ignoring case.
+// Admins only.
+func (c *Client) BanPattern(ctx context.Context, pattern string) error {
+	body := map[string]string{"pattern": pattern}
+	_, err := c.do(ctx, &request{method: "POST", path: "/usernames/banned", body: body, auth: true}, nil)
+	return err
+}
+
+// UnbanPattern removes a pattern from the banned patterns. Admins only.
+func (c *Client) UnbanPattern(ctx context.Context, pattern string) error {
+	query := url.Values{"pattern": {pattern}}
+	_, err := c.do(ctx, &request{method: "DELETE", path: "/usernames/banned", query: query, auth: true}, nil)
+	return err
+}
@@ -0,0 +1,186 @@
+// Package client is a typed Go client for the API of the service.
+//
+// sn - https://github.com/sn
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sn/service/types"
+)
+
+// User is a user of the service
+type User struct {
+	ID          types.UUID
+	Username    string
+	Address     *mail.Address
+	Role        string
+	Deactivated bool
+	Created     time.Time
+	Updated     time.Time
+	Version     int
+}
+
+// UserInput is a user to create, or to replace a user with
+type UserInput struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Email    string `json:"email"`
+}
+
+// UserPatch changes the fields of a user that are not nil
+type UserPatch struct {
+	Username *string `json:"username,omitempty"`
+	Password *string `json:"password,omitempty"`
+	Email    *string `json:"email,omitempty"`
+	// Role is user or admin, and only admins can change it
+	Role *string `json:"role,omitempty"`
+}
+
+// ListOptions filter and sort a list of users. Zero values are left to the
+// service's defaults.
+type ListOptions struct {
+	Limit          int
+	Cursor         string
+	UsernamePrefix string
+	Role           string
+	CreatedAfter   time.Time
+	CreatedBefore  time.Time
+	// Sort is created or username, prefixed by "-" for descending order
+	Sort string
+}
+
+// query returns the query parameters of the options
+func (o ListOptions) query() url.Values {
+	q := url.Values{}
+	if o.Limit > 0 {
+		q.Set("limit", strconv.Itoa(o.Limit))
+	}
+	if o.Cursor != "" {
+		q.Set("cursor", o.Cursor)
+	}
+	if o.UsernamePrefix != "" {
+		q.Set("username_prefix", o.UsernamePrefix)
+	}
+	if o.Role != "" {
+		q.Set("role", o.Role)
+	}
+	if !o.CreatedAfter.IsZero() {
+		q.Set("created_after", o.CreatedAfter.Format(time.RFC3339Nano))
+	}
+	if !o.CreatedBefore.IsZero() {
+		q.Set("created_before", o.CreatedBefore.Format(time.RFC3339Nano))
+	}
+	if o.Sort != "" {
+		q.Set("sort", o.Sort)
+	}
+	return q
+}
+
+// UserPage is a page of users
+type UserPage struct {
+	Users []User
+	// Next is the cursor of the next page, or "" on the last page
+	Next string
+}
+
+// Users lists a page of users
+func (c *Client) Users(ctx context.Context, opts ListOptions) (*UserPage, error) {
+	page := &UserPage{}
+	header, err := c.do(ctx, &request{method: "GET", path: "/users", query: opts.query(), auth: true}, &page.Users)
+	if err != nil {
+		return nil, err
+	}
+	page.Next = nextCursor(header)
+	return page, nil
+}
+
+// EachUser calls fn with every user matching the options, a page at a time,
+// until fn returns an error
+func (c *Client) EachUser(ctx context.Context, opts ListOptions, fn func(User) error) error {
+	for {
+		page, err := c.Users(ctx, opts)
+		if err != nil {
+			return err
+		}
+		for _, u := range page.Users {
+			if err := fn(u); err != nil {
+				return err
+			}
+		}
+		if page.Next == "" {
+			return nil
+		}
+		opts.Cursor = page.Next
+	}
+}
+
+// nextCursor returns the cursor of the next page in a Link header
+func nextCursor(header http.Header) string {
+	for _, link := range strings.Split(header.Get("Link"), ",") {
+		parts := strings.Split(link, ";")
+		if len(parts) < 2 || strings.TrimSpace(parts[1]) != `rel="next"` {
+			continue
+		}
+		u, err := url.Parse(strings.Trim(strings.TrimSpace(parts[0]), "<>"))
+		if err != nil {
+			return ""
+		}
+		return u.Query().Get("cursor")
+	}
+	return ""
+}
+
+// User returns a user
+func (c *Client) User(ctx context.Context, id types.UUID) (*User, error) {
+	u := &User{}
+	if _, err := c.do(ctx, &request{method: "GET", path: userPath(id), auth: true}, u); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// CreateUser creates a user
+func (c *Client) CreateUser(ctx context.Context, input UserInput) (*User, error) {
+	u := &User{}
+	if _, err := c.do(ctx, &request{method: "POST", path: "/users", body: input, auth: true}, u); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// ReplaceUser replaces the username, password and address of a user
+func (c *Client) ReplaceUser(ctx context.Context, id types.UUID, input UserInput) (*User, error) {
+	u := &User{}
+	if _, err := c.do(ctx, &request{method: "PUT", path: userPath(id), body: input, auth: true}, u); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// PatchUser changes some fields of a user
+func (c *Client) PatchUser(ctx context.Context, id types.UUID, patch UserPatch) (*User, error) {
+	u := &User{}
+	req := &request{method: "PATCH", path: userPath(id), body: patch, contentType: "application/merge-patch+json", auth: true}
+	if _, err := c.do(ctx, req, u); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// DeleteUser deletes a user
+func (c *Client) DeleteUser(ctx context.Context, id types.UUID) error {
+	_, err := c.do(ctx, &request{method: "DELETE", path: userPath(id), auth: true}, nil)
+	return err
+}
+
+// userPath returns the path of a user
+func userPath(id types.UUID) string {
+	return "/users/" + url.PathEscape(string(id))
+}
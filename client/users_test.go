@@ -0,0 +1,81 @@
+// Package client is a typed Go client for the API of the service.
+//
+// sn - https://github.com/sn
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestUsers(t *testing.T) {
+	c := newClient(t, nil)
+	ctx := context.Background()
+
+	var created []*User
+	for i := 0; i < 5; i++ {
+		name := fmt.Sprintf("listed%d", i)
+		u, err := c.CreateUser(ctx, UserInput{Username: name, Password: "1@E4s67890", Email: name + "@example.com"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		created = append(created, u)
+	}
+	if _, err := c.CreateUser(ctx, UserInput{Username: "listed0", Password: "1@E4s67890", Email: "other@example.com"}); !errors.Is(err, ErrConflict) {
+		t.Errorf("Expected ErrConflict, got %v.", err)
+	}
+
+	page, err := c.Users(ctx, ListOptions{Limit: 2, UsernamePrefix: "listed", Sort: "username"})
+	if err != nil || len(page.Users) != 2 || page.Users[0].Username != "listed0" || page.Next == "" {
+		t.Fatalf("Expected the first page, got %+v, %v.", page, err)
+	}
+	var names []string
+	err = c.EachUser(ctx, ListOptions{Limit: 2, UsernamePrefix: "listed", Sort: "-username"}, func(u User) error {
+		names = append(names, u.Username)
+		return nil
+	})
+	if err != nil || fmt.Sprint(names) != "[listed4 listed3 listed2 listed1 listed0]" {
+		t.Errorf("Expected every user in order, got %v, %v.", names, err)
+	}
+
+	id := created[0].ID
+	u, err := c.User(ctx, id)
+	if err != nil || u.Username != "listed0" || u.Address.Address != "listed0@example.com" {
+		t.Errorf("Expected the user, got %+v, %v.", u, err)
+	}
+	username := "patched"
+	if u, err = c.PatchUser(ctx, id, UserPatch{Username: &username}); err != nil || u.Username != "patched" || u.Address.Address != "listed0@example.com" {
+		t.Errorf("Expected the username to be patched, got %+v, %v.", u, err)
+	}
+	if u, err = c.ReplaceUser(ctx, id, UserInput{Username: "replaced", Password: "1@E4s67890", Email: "replaced@example.com"}); err != nil || u.Username != "replaced" {
+		t.Errorf("Expected the user to be replaced, got %+v, %v.", u, err)
+	}
+	if err := c.DeleteUser(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.User(ctx, id); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound after deleting, got %v.", err)
+	}
+}
+
+func TestNextCursor(t *testing.T) {
+	tests := map[string]string{
+		"":                                   "",
+		`</v1/users?cursor=abc>; rel="next"`: "abc",
+		`</v1/users?cursor=a%2Bb&limit=2>; rel="prev", </v1/users?cursor=c%2Bd&limit=2>; rel="next"`: "c+d",
+		`</v1/users?cursor=abc>; rel="prev"`: "",
+	}
+	for link, want := range tests {
+		header := http.Header{"Link": {link}}
+		if got := nextCursor(header); got != want {
+			t.Errorf("%q: expected %q, got %q.", link, want, got)
+		}
+	}
+	if q := (ListOptions{Limit: 5, Role: "admin"}).query(); q.Encode() != (url.Values{"limit": {"5"}, "role": {"admin"}}).Encode() {
+		t.Errorf("Expected only the set options, got %v.", q)
+	}
+}
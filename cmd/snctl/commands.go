@@ -0,0 +1,288 @@
+// Package snctl is a command line tool that manages the service through its
+// API.
+//
+// sn - https://github.com/sn
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sn/service/client"
+	"github.com/sn/service/types"
+)
+
+// errStop stops listing users once the limit is reached
+var errStop = errors.New("stop")
+
+// flags returns a flag set of a command, whose errors are reported by
+// returning errUsage
+func flags(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	return fs
+}
+
+// argID returns the only argument of a command taking an ID
+func argID(fs *flag.FlagSet, args []string) (types.UUID, error) {
+	if err := fs.Parse(args); err != nil || fs.NArg() != 1 {
+		return "", errUsage
+	}
+	return types.UUID(fs.Arg(0)), nil
+}
+
+// passwordClasses are the characters a password must have one of each
+var passwordClasses = []string{
+	"abcdefghijklmnopqrstuvwxyz",
+	"ABCDEFGHIJKLMNOPQRSTUVWXYZ",
+	"0123456789",
+	"!#$%&*+-.:=?@^_~",
+}
+
+// generatePassword returns a random password of 20 characters, with at least
+// one of each of passwordClasses
+func generatePassword() (string, error) {
+	all := strings.Join(passwordClasses, "")
+	for {
+		b := make([]byte, 20)
+		if _, err := rand.Read(b); err != nil {
+			return "", err
+		}
+		for i := range b {
+			b[i] = all[int(b[i])%len(all)]
+		}
+		password := string(b)
+		valid := true
+		for _, class := range passwordClasses {
+			valid = valid && strings.ContainsAny(password, class)
+		}
+		if valid {
+			return password, nil
+		}
+	}
+}
+
+func login(ctx context.Context, c *cli, args []string) error {
+	if len(args) != 0 {
+		return errUsage
+	}
+	token := c.client.Token()
+	if token == "" {
+		return fmt.Errorf("Logging in requires -user-id and -password.")
+	}
+	fmt.Fprintln(c.stdout, token)
+	return nil
+}
+
+func usersList(ctx context.Context, c *cli, args []string) error {
+	fs := flags("users list")
+	var opts client.ListOptions
+	fs.StringVar(&opts.UsernamePrefix, "prefix", "", "")
+	fs.StringVar(&opts.Role, "role", "", "")
+	fs.StringVar(&opts.Sort, "sort", "", "")
+	limit := fs.Int("limit", 0, "")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 0 || *limit < 0 {
+		return errUsage
+	}
+	users := []client.User{}
+	err := c.client.EachUser(ctx, opts, func(u client.User) error {
+		if *limit > 0 && len(users) == *limit {
+			return errStop
+		}
+		users = append(users, u)
+		return nil
+	})
+	if err != nil && err != errStop {
+		return err
+	}
+	return c.printUsers(users)
+}
+
+func usersShow(ctx context.Context, c *cli, args []string) error {
+	id, err := argID(flags("users show"), args)
+	if err != nil {
+		return err
+	}
+	u, err := c.client.User(ctx, id)
+	if err != nil {
+		return err
+	}
+	return c.printUsers([]client.User{*u})
+}
+
+func usersCreate(ctx context.Context, c *cli, args []string) error {
+	fs := flags("users create")
+	var input client.UserInput
+	fs.StringVar(&input.Username, "username", "", "")
+	fs.StringVar(&input.Email, "email", "", "")
+	fs.StringVar(&input.Password, "password", "", "")
+	admin := fs.Bool("admin", false, "")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 0 || input.Username == "" || input.Email == "" {
+		return errUsage
+	}
+	generated := input.Password == ""
+	if generated {
+		var err error
+		if input.Password, err = generatePassword(); err != nil {
+			return err
+		}
+	}
+	u, err := c.client.CreateUser(ctx, input)
+	if err != nil {
+		return err
+	}
+	if generated {
+		fmt.Fprintf(c.stderr, "Password: %s\n", input.Password)
+	}
+	if *admin {
+		if u, err = c.setRole(ctx, u.ID, "admin"); err != nil {
+			return err
+		}
+	}
+	return c.printUsers([]client.User{*u})
+}
+
+func usersDelete(ctx context.Context, c *cli, args []string) error {
+	id, err := argID(flags("users delete"), args)
+	if err != nil {
+		return err
+	}
+	return c.client.DeleteUser(ctx, id)
+}
+
+func usersResetPassword(ctx context.Context, c *cli, args []string) error {
+	fs := flags("users reset-password")
+	password := fs.String("password", "", "")
+	id, err := argID(fs, args)
+	if err != nil {
+		return err
+	}
+	generated := *password == ""
+	if generated {
+		if *password, err = generatePassword(); err != nil {
+			return err
+		}
+	}
+	if _, err := c.client.PatchUser(ctx, id, client.UserPatch{Password: password}); err != nil {
+		return err
+	}
+	if generated {
+		fmt.Fprintf(c.stderr, "Password: %s\n", *password)
+	}
+	return nil
+}
+
+func usersPromote(ctx context.Context, c *cli, args []string) error {
+	id, err := argID(flags("users promote"), args)
+	if err != nil {
+		return err
+	}
+	u, err := c.setRole(ctx, id, "admin")
+	if err != nil {
+		return err
+	}
+	return c.printUsers([]client.User{*u})
+}
+
+func usersDemote(ctx context.Context, c *cli, args []string) error {
+	id, err := argID(flags("users demote"), args)
+	if err != nil {
+		return err
+	}
+	u, err := c.setRole(ctx, id, "user")
+	if err != nil {
+		return err
+	}
+	return c.printUsers([]client.User{*u})
+}
+
+// setRole changes the role of a user
+func (c *cli) setRole(ctx context.Context, id types.UUID, role string) (*client.User, error) {
+	return c.client.PatchUser(ctx, id, client.UserPatch{Role: &role})
+}
+
+// printUsers prints users, as a table of their ID, username, address, role
+// and creation time
+func (c *cli) printUsers(users []client.User) error {
+	rows := make([][]string, 0, len(users))
+	for _, u := range users {
+		email := ""
+		if u.Address != nil {
+			email = u.Address.Address
+		}
+		rows = append(rows, []string{string(u.ID), u.Username, email, u.Role, strconv.FormatBool(u.Deactivated), u.Created.Format(time.RFC3339)})
+	}
+	return c.print(users, []string{"ID", "USERNAME", "EMAIL", "ROLE", "DEACTIVATED", "CREATED"}, rows)
+}
+
+func sessionsList(ctx context.Context, c *cli, args []string) error {
+	fs := flags("sessions list")
+	userID := fs.String("user", "", "")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 0 {
+		return errUsage
+	}
+	sessions, err := c.client.Sessions(ctx, types.UUID(*userID))
+	if err != nil {
+		return err
+	}
+	return c.printSessions(sessions)
+}
+
+func sessionsRevoke(ctx context.Context, c *cli, args []string) error {
+	id, err := argID(flags("sessions revoke"), args)
+	if err != nil {
+		return err
+	}
+	return c.client.RevokeSession(ctx, id)
+}
+
+// printSessions prints sessions, as a table of their ID, user ID and
+// expiration time
+func (c *cli) printSessions(sessions []client.Session) error {
+	if sessions == nil {
+		sessions = []client.Session{}
+	}
+	rows := make([][]string, 0, len(sessions))
+	for _, s := range sessions {
+		rows = append(rows, []string{string(s.ID), string(s.UserID), s.Expires.Format(time.RFC3339)})
+	}
+	return c.print(sessions, []string{"ID", "USER ID", "EXPIRES"}, rows)
+}
+
+func export(ctx context.Context, c *cli, args []string) error {
+	if len(args) != 0 {
+		return errUsage
+	}
+	data := struct {
+		Users    []client.User
+		Sessions []client.Session
+	}{Users: []client.User{}}
+	err := c.client.EachUser(ctx, client.ListOptions{}, func(u client.User) error {
+		data.Users = append(data.Users, u)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if data.Sessions, err = c.client.Sessions(ctx, ""); err != nil {
+		return err
+	}
+	if data.Sessions == nil {
+		data.Sessions = []client.Session{}
+	}
+	if c.format == FormatJSON {
+		return c.print(data, nil, nil)
+	}
+	if err := c.printUsers(data.Users); err != nil {
+		return err
+	}
+	fmt.Fprintln(c.stdout)
+	return c.printSessions(data.Sessions)
+}
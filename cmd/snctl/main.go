@@ -0,0 +1,169 @@
+// Package snctl is a command line tool that manages the service through its
+// API.
+//
+// sn - https://github.com/sn
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/sn/service/client"
+	"github.com/sn/service/types"
+)
+
+// Output formats
+const (
+	FormatTable = "table"
+	FormatJSON  = "json"
+)
+
+// errUsage is returned by commands called with the wrong arguments
+var errUsage = errors.New("usage")
+
+// cli is the state shared by the commands
+type cli struct {
+	client *client.Client
+	format string
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// command is a subcommand of snctl, named by one or two words
+type command struct {
+	name  string
+	args  string
+	usage string
+	run   func(ctx context.Context, c *cli, args []string) error
+}
+
+var commands = []command{
+	{"login", "", "start a session and print its token", login},
+	{"users list", "[-prefix p] [-role r] [-sort s] [-limit n]", "list users", usersList},
+	{"users show", "ID", "show a user", usersShow},
+	{"users create", "-username u -email e [-password p] [-admin]", "create a user, generating a password if none is given", usersCreate},
+	{"users delete", "ID", "delete a user", usersDelete},
+	{"users reset-password", "[-password p] ID", "set the password of a user, generating one if none is given", usersResetPassword},
+	{"users promote", "ID", "give a user the admin role", usersPromote},
+	{"users demote", "ID", "give a user the user role", usersDemote},
+	{"sessions list", "[-user ID]", "list unexpired sessions", sessionsList},
+	{"sessions revoke", "ID", "end a session", sessionsRevoke},
+	{"usernames reserved", "", "list the reserved usernames", usernamesReserved},
+	{"usernames reserve", "NAME", "reserve a username", usernamesReserve},
+	{"usernames unreserve", "NAME", "stop reserving a username", usernamesUnreserve},
+	{"usernames banned", "", "list the banned username patterns", usernamesBanned},
+	{"usernames ban", "PATTERN", "ban usernames matching a regular expression, ignoring case", usernamesBan},
+	{"usernames unban", "PATTERN", "remove a banned pattern", usernamesUnban},
+	{"export", "", "print every user and session", export},
+}
+
+func main() {
+	os.Exit(run(context.Background(), os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run runs snctl with arguments, and returns its exit code
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("snctl", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	baseURL := fs.String("url", env("SN_URL", "http://localhost:8080"), "base URL of the service")
+	token := fs.String("token", os.Getenv("SN_TOKEN"), "session token")
+	userID := fs.String("user-id", os.Getenv("SN_USER_ID"), "ID of the user to log in as")
+	password := fs.String("password", os.Getenv("SN_PASSWORD"), "password of the user to log in as")
+	format := fs.String("o", FormatTable, "output format, table or json")
+	fs.Usage = func() { usage(fs, stderr) }
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *format != FormatTable && *format != FormatJSON {
+		fmt.Fprintln(stderr, "Output format must be table or json.")
+		return 2
+	}
+	cmd, args := lookup(fs.Args())
+	if cmd == nil {
+		fs.Usage()
+		return 2
+	}
+
+	c, err := client.New(*baseURL)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	if *userID != "" {
+		if err := c.Login(ctx, types.UUID(*userID), *password); err != nil {
+			fmt.Fprintf(stderr, "Unable to log in: %v\n", err)
+			return 1
+		}
+	} else if *token != "" {
+		c.SetToken(*token)
+	}
+
+	err = cmd.run(ctx, &cli{client: c, format: *format, stdout: stdout, stderr: stderr}, args)
+	if err == errUsage {
+		fmt.Fprintf(stderr, "Usage: snctl %s %s\n", cmd.name, cmd.args)
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// lookup returns the command named by the first words of args, and the
+// arguments following its name
+func lookup(args []string) (*command, []string) {
+	for i := range commands {
+		words := strings.Fields(commands[i].name)
+		if len(args) < len(words) {
+			continue
+		}
+		if strings.Join(args[:len(words)], " ") == commands[i].name {
+			return &commands[i], args[len(words):]
+		}
+	}
+	return nil, nil
+}
+
+// usage prints the flags and commands of snctl
+func usage(fs *flag.FlagSet, w io.Writer) {
+	fmt.Fprintln(w, "Usage: snctl [flags] command [arguments]")
+	fmt.Fprintln(w, "\nFlags:")
+	fs.PrintDefaults()
+	fmt.Fprintln(w, "\nCommands:")
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, cmd := range commands {
+		fmt.Fprintf(tw, "  %s %s\t%s\n", cmd.name, cmd.args, cmd.usage)
+	}
+	tw.Flush()
+}
+
+// env returns an environment variable, or a default if it is not set
+func env(key, def string) string {
+	if v, ok := os.LookupEnv(key); ok {
+		return v
+	}
+	return def
+}
+
+// print writes v as JSON, or a table of a header and rows
+func (c *cli) print(v interface{}, header []string, rows [][]string) error {
+	if c.format == FormatJSON {
+		enc := json.NewEncoder(c.stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+	tw := tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
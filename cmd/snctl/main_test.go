@@ -0,0 +1,171 @@
+// Package snctl is a command line tool that manages the service through its
+// API.
+//
+// sn - https://github.com/sn
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"net/mail"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/sn/service/client"
+	"github.com/sn/service/router"
+	"github.com/sn/service/session"
+	"github.com/sn/service/user"
+)
+
+var (
+	server *httptest.Server
+	admin  user.User
+)
+
+// snctl runs snctl against the test server as the admin, and returns its
+// exit code and output
+func snctl(args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	args = append([]string{"-url", server.URL, "-user-id", string(admin.ID), "-password", "1@E4s67890"}, args...)
+	code := run(context.Background(), args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestUsage(t *testing.T) {
+	tests := [][]string{
+		{},
+		{"users"},
+		{"unknown"},
+		{"-o", "yaml", "users", "list"},
+		{"users", "show"},
+		{"users", "create", "-username", "quinn"},
+		{"users", "list", "-limit", "-1"},
+	}
+	for _, args := range tests {
+		if code, _, _ := snctl(args...); code != 2 {
+			t.Errorf("%v: expected exit code 2, got %d.", args, code)
+		}
+	}
+	if code, _, stderr := snctl("users", "show", "3ddb2e4c-1d0c-4d4f-8b47-7d5dbe1e6c0d"); code != 1 || !strings.Contains(stderr, "404") {
+		t.Errorf("Expected the error of the service, got %d, %q.", code, stderr)
+	}
+}
+
+func TestUsers(t *testing.T) {
+	code, stdout, stderr := snctl("-o", "json", "users", "create", "-username", "jordan", "-email", "jordan@example.com")
+	var created []client.User
+	if code != 0 || json.Unmarshal([]byte(stdout), &created) != nil || len(created) != 1 {
+		t.Fatalf("Expected the created user, got %d, %q, %q.", code, stdout, stderr)
+	}
+	id := string(created[0].ID)
+	password := strings.TrimSpace(strings.TrimPrefix(stderr, "Password: "))
+	if !user.CheckPassword(context.Background(), user.FindByID(context.Background(), created[0].ID), password) {
+		t.Errorf("Expected the generated password to be printed, got %q.", stderr)
+	}
+
+	code, stdout, _ = snctl("users", "list", "-prefix", "jor")
+	if code != 0 || !strings.HasPrefix(stdout, "ID ") || !strings.Contains(stdout, "jordan@example.com") {
+		t.Errorf("Expected a table of the user, got %d, %q.", code, stdout)
+	}
+	var listed []client.User
+	if code, stdout, _ = snctl("-o", "json", "users", "list", "-limit", "1"); code != 0 || json.Unmarshal([]byte(stdout), &listed) != nil || len(listed) != 1 {
+		t.Errorf("Expected one user, got %d, %q.", code, stdout)
+	}
+
+	if code, _, _ = snctl("users", "promote", id); code != 0 || user.FindByID(context.Background(), created[0].ID).Role != user.RoleAdmin {
+		t.Errorf("Expected the user to be promoted, got %d.", code)
+	}
+	if code, _, _ = snctl("users", "demote", id); code != 0 || user.FindByID(context.Background(), created[0].ID).Role != user.RoleUser {
+		t.Errorf("Expected the user to be demoted, got %d.", code)
+	}
+	if code, _, _ = snctl("users", "reset-password", "-password", "0!A3r56789", id); code != 0 || !user.CheckPassword(context.Background(), user.FindByID(context.Background(), created[0].ID), "0!A3r56789") {
+		t.Errorf("Expected the password to be reset, got %d.", code)
+	}
+
+	if code, _, _ = snctl("users", "delete", id); code != 0 {
+		t.Errorf("Expected the user to be deleted, got %d.", code)
+	}
+	if code, _, _ = snctl("users", "show", id); code != 1 {
+		t.Errorf("Expected the user to be gone, got %d.", code)
+	}
+}
+
+func TestSessions(t *testing.T) {
+	code, token, _ := snctl("login")
+	token = strings.TrimSpace(token)
+	s := session.Find(context.Background(), token)
+	if code != 0 || s.ID == "" {
+		t.Fatalf("Expected a session token, got %d, %q.", code, token)
+	}
+
+	var sessions []client.Session
+	code, stdout, _ := snctl("-o", "json", "sessions", "list", "-user", string(admin.ID))
+	if code != 0 || json.Unmarshal([]byte(stdout), &sessions) != nil || len(sessions) == 0 {
+		t.Fatalf("Expected the sessions of the admin, got %d, %q.", code, stdout)
+	}
+	if code, _, _ = snctl("sessions", "revoke", string(s.PublicID)); code != 0 || session.Get(s.ID).ID != "" {
+		t.Errorf("Expected the session to be revoked, got %d.", code)
+	}
+}
+
+func TestUsernames(t *testing.T) {
+	if code, _, _ := snctl("usernames", "reserve", "Mallöry"); code != 0 || !user.IsReserved("mallöry") {
+		t.Errorf("Expected the username to be reserved, got %d.", code)
+	}
+	if code, stdout, _ := snctl("usernames", "reserved"); code != 0 || !strings.HasPrefix(stdout, "USERNAME") || !strings.Contains(stdout, "Mallöry") {
+		t.Errorf("Expected the reserved usernames, got %d, %q.", code, stdout)
+	}
+	if code, _, _ := snctl("usernames", "unreserve", "Mallöry"); code != 0 || user.IsReserved("mallöry") {
+		t.Errorf("Expected the username to be unreserved, got %d.", code)
+	}
+
+	if code, _, _ := snctl("usernames", "ban", "^troll"); code != 0 || !user.IsReserved("Trollface") {
+		t.Errorf("Expected the pattern to be banned, got %d.", code)
+	}
+	var patterns []string
+	if code, stdout, _ := snctl("-o", "json", "usernames", "banned"); code != 0 || json.Unmarshal([]byte(stdout), &patterns) != nil || len(patterns) != 1 {
+		t.Errorf("Expected the banned patterns, got %d, %q.", code, stdout)
+	}
+	if code, _, _ := snctl("usernames", "unban", "^troll"); code != 0 || user.IsReserved("Trollface") {
+		t.Errorf("Expected the pattern to be unbanned, got %d.", code)
+	}
+}
+
+func TestExport(t *testing.T) {
+	code, stdout, _ := snctl("-o", "json", "export")
+	var data struct {
+		Users    []client.User
+		Sessions []client.Session
+	}
+	if code != 0 || json.Unmarshal([]byte(stdout), &data) != nil || len(data.Users) != len(user.GetAll()) || len(data.Sessions) == 0 {
+		t.Errorf("Expected every user and session, got %d, %q.", code, stdout)
+	}
+	if code, stdout, _ = snctl("export"); code != 0 || !strings.Contains(stdout, "USERNAME") || !strings.Contains(stdout, "EXPIRES") {
+		t.Errorf("Expected tables of users and sessions, got %d, %q.", code, stdout)
+	}
+}
+
+func TestGeneratePassword(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		password, err := generatePassword()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := user.Validate(user.User{Password: password}); err != nil {
+			t.Errorf("%q: %v", password, err)
+		}
+	}
+}
+
+func TestMain(m *testing.M) {
+	router.RateLimits.Requests = 0
+	server = httptest.NewServer(router.NewRouter())
+	addr, _ := mail.ParseAddress("admin@example.com")
+	admin = user.Create(context.Background(), user.User{Username: "admin", Password: "1@E4s67890", Address: addr, Role: user.RoleAdmin})
+	code := m.Run()
+	server.Close()
+	os.Exit(code)
+}
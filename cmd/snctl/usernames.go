@@ -0,0 +1,83 @@
+// Package snctl is a command line tool that manages the service through its
+// API.
+//
+// sn - https://github.com/sn
+package main
+
+import (
+	"context"
+)
+
+// arg returns the only argument of a command
+func arg(args []string) (string, error) {
+	if len(args) != 1 || args[0] == "" {
+		return "", errUsage
+	}
+	return args[0], nil
+}
+
+// printList prints a list of strings, as a table of one column
+func (c *cli) printList(list []string, column string) error {
+	if list == nil {
+		list = []string{}
+	}
+	rows := make([][]string, 0, len(list))
+	for _, s := range list {
+		rows = append(rows, []string{s})
+	}
+	return c.print(list, []string{column}, rows)
+}
+
+func usernamesReserved(ctx context.Context, c *cli, args []string) error {
+	if len(args) != 0 {
+		return errUsage
+	}
+	names, err := c.client.ReservedUsernames(ctx)
+	if err != nil {
+		return err
+	}
+	return c.printList(names, "USERNAME")
+}
+
+func usernamesReserve(ctx context.Context, c *cli, args []string) error {
+	name, err := arg(args)
+	if err != nil {
+		return err
+	}
+	return c.client.ReserveUsername(ctx, name)
+}
+
+func usernamesUnreserve(ctx context.Context, c *cli, args []string) error {
+	name, err := arg(args)
+	if err != nil {
+		return err
+	}
+	return c.client.UnreserveUsername(ctx, name)
+}
+
+func usernamesBanned(ctx context.Context, c *cli, args []string) error {
+	if len(args) != 0 {
+		return errUsage
+	}
+	patterns, err := c.client.BannedPatterns(ctx)
+	if err != nil {
+		return err
+	}
+	return c.printList(patterns, "PATTERN")
+}
+
+func usernamesBan(ctx context.Context, c *cli, args []string) error {
+	pattern, err := arg(args)
+	if err != nil {
+		return err
+	}
+	return c.client.BanPattern(ctx, pattern)
+}
+
+func usernamesUnban(ctx context.Context, c *cli, args []string) error {
+	pattern, err := arg(args)
+	if err != nil {
+		return err
+	}
+	return c.client.UnbanPattern(ctx, pattern)
+}
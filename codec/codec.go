@@ -0,0 +1,212 @@
+// Package codec encodes and decodes request and response bodies in the media
+// types the service supports, and negotiates them with clients.
+//
+// sn - https://github.com/sn
+package codec
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Codec encodes and decodes values in a media type. Struct fields are named
+// by their json tags in every media type.
+type Codec interface {
+	// MediaType is the media type of the codec, sent in Content-Type
+	MediaType() string
+	// Matches reports whether a media type, without parameters, is the
+	// codec's media type or an alias of it
+	Matches(mediaType string) bool
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// ErrUnsupported is returned when no codec matches a media type
+var ErrUnsupported = fmt.Errorf("Unsupported media type.")
+
+// ErrNotAcceptable is returned when no codec is acceptable to a client
+var ErrNotAcceptable = fmt.Errorf("Not acceptable.")
+
+var (
+	// JSON encodes values as JSON
+	JSON Codec = jsonCodec{}
+
+	// MessagePack encodes values as MessagePack
+	MessagePack Codec = msgpackCodec{}
+
+	// CBOR encodes values as CBOR (RFC 8949)
+	CBOR Codec = newCBORCodec()
+)
+
+// Codecs are the supported codecs, in order of preference
+var Codecs = []Codec{JSON, MessagePack, CBOR}
+
+// jsonCodec is the JSON codec
+type jsonCodec struct{}
+
+func (jsonCodec) MediaType() string { return "application/json" }
+
+func (jsonCodec) Matches(mediaType string) bool {
+	return mediaType == "application/json"
+}
+
+// Marshal encodes a value as JSON followed by a newline, like json.Encoder
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	err := json.NewEncoder(&buf).Encode(v)
+	return buf.Bytes(), err
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// msgpackCodec is the MessagePack codec
+type msgpackCodec struct{}
+
+func (msgpackCodec) MediaType() string { return "application/msgpack" }
+
+func (msgpackCodec) Matches(mediaType string) bool {
+	switch mediaType {
+	case "application/msgpack", "application/x-msgpack", "application/vnd.msgpack":
+		return true
+	}
+	return false
+}
+
+func (msgpackCodec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetCustomStructTag("json")
+	err := enc.Encode(v)
+	return buf.Bytes(), err
+}
+
+func (msgpackCodec) Unmarshal(data []byte, v interface{}) error {
+	dec := msgpack.NewDecoder(bytes.NewReader(data))
+	dec.SetCustomStructTag("json")
+	return dec.Decode(v)
+}
+
+// cborCodec is the CBOR codec. Times are encoded as RFC 3339 strings, and
+// maps are decoded with string keys, as in JSON. Strings are not checked for
+// valid UTF-8 when decoded, so whatever Go strings are encoded decode again.
+type cborCodec struct {
+	enc cbor.EncMode
+	dec cbor.DecMode
+}
+
+func newCBORCodec() cborCodec {
+	enc, err := cbor.EncOptions{Time: cbor.TimeRFC3339Nano}.EncMode()
+	if err != nil {
+		panic(err)
+	}
+	dec, err := cbor.DecOptions{
+		DefaultMapType: reflect.TypeOf(map[string]interface{}{}),
+		UTF8:           cbor.UTF8DecodeInvalid,
+	}.DecMode()
+	if err != nil {
+		panic(err)
+	}
+	return cborCodec{enc: enc, dec: dec}
+}
+
+func (cborCodec) MediaType() string { return "application/cbor" }
+
+func (cborCodec) Matches(mediaType string) bool {
+	return mediaType == "application/cbor"
+}
+
+func (c cborCodec) Marshal(v interface{}) ([]byte, error) {
+	return c.enc.Marshal(v)
+}
+
+func (c cborCodec) Unmarshal(data []byte, v interface{}) error {
+	return c.dec.Unmarshal(data, v)
+}
+
+// ForContentType returns the codec of a Content-Type header. An empty header
+// is taken to be JSON.
+func ForContentType(contentType string) (Codec, error) {
+	if contentType == "" {
+		return JSON, nil
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, ErrUnsupported
+	}
+	for _, c := range Codecs {
+		if c.Matches(mediaType) {
+			return c, nil
+		}
+	}
+	return nil, ErrUnsupported
+}
+
+// acceptRange is a media range of an Accept header
+type acceptRange struct {
+	mediaType string
+	q         float64
+}
+
+// matches reports whether a media range includes the media type of a codec
+func (a acceptRange) matches(c Codec) bool {
+	if a.mediaType == "*/*" || c.Matches(a.mediaType) {
+		return true
+	}
+	return strings.HasSuffix(a.mediaType, "/*") && strings.HasPrefix(c.MediaType(), strings.TrimSuffix(a.mediaType, "*"))
+}
+
+// Negotiate returns the codec a client prefers by its Accept header, using the
+// most specific media range matching each codec. Ties go to the order of
+// Codecs, and an empty header accepts JSON.
+func Negotiate(accept string) (Codec, error) {
+	if strings.TrimSpace(accept) == "" {
+		return JSON, nil
+	}
+	var ranges []acceptRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if s, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(s, 64); err != nil {
+				continue
+			}
+		}
+		ranges = append(ranges, acceptRange{mediaType, q})
+	}
+	// most specific first, so the first matching range of a codec applies
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return strings.Count(ranges[i].mediaType, "*") < strings.Count(ranges[j].mediaType, "*")
+	})
+
+	var best Codec
+	bestQ := 0.0
+	for _, c := range Codecs {
+		for _, r := range ranges {
+			if !r.matches(c) {
+				continue
+			}
+			if r.q > bestQ {
+				best, bestQ = c, r.q
+			}
+			break
+		}
+	}
+	if best == nil {
+		return nil, ErrNotAcceptable
+	}
+	return best, nil
+}
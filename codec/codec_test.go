@@ -0,0 +1,85 @@
+// Package codec encodes and decodes request and response bodies in the media
+// types the service supports, and negotiates them with clients.
+//
+// sn - https://github.com/sn
+package codec
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type record struct {
+	Name    string    `json:"name"`
+	Count   int       `json:"count"`
+	Tags    []string  `json:"tags,omitempty"`
+	Created time.Time `json:"created"`
+}
+
+func TestRoundTrip(t *testing.T) {
+	in := record{Name: "alex", Count: 3, Tags: []string{"a", "b"}, Created: time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC)}
+	for _, c := range Codecs {
+		b, err := c.Marshal(in)
+		if err != nil {
+			t.Errorf("%s: %v", c.MediaType(), err)
+			continue
+		}
+		var out record
+		if err := c.Unmarshal(b, &out); err != nil {
+			t.Errorf("%s: %v", c.MediaType(), err)
+			continue
+		}
+		if !reflect.DeepEqual(in.Tags, out.Tags) || in.Name != out.Name || in.Count != out.Count || !in.Created.Equal(out.Created) {
+			t.Errorf("%s: expected %+v, got %+v.", c.MediaType(), in, out)
+		}
+
+		var generic map[string]interface{}
+		if err := c.Unmarshal(b, &generic); err != nil {
+			t.Errorf("%s: %v", c.MediaType(), err)
+		}
+		if generic["name"] != "alex" {
+			t.Errorf("%s: expected fields to be named by their json tags, got %v.", c.MediaType(), generic)
+		}
+	}
+}
+
+func TestForContentType(t *testing.T) {
+	tests := map[string]Codec{
+		"":                                JSON,
+		"application/json; charset=UTF-8": JSON,
+		"application/msgpack":             MessagePack,
+		"application/x-msgpack":           MessagePack,
+		"application/cbor":                CBOR,
+		"text/plain":                      nil,
+		"garbage;":                        nil,
+	}
+	for contentType, want := range tests {
+		c, err := ForContentType(contentType)
+		if c != want || (want == nil) != (err == ErrUnsupported) {
+			t.Errorf("%q: expected %v, got %v, %v.", contentType, want, c, err)
+		}
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := map[string]Codec{
+		"":                                      JSON,
+		"*/*":                                   JSON,
+		"application/*":                         JSON,
+		"application/cbor":                      CBOR,
+		"application/msgpack, application/json": JSON,
+		"application/json;q=0.5, application/msgpack":         MessagePack,
+		"application/cbor;q=0.9, */*;q=0.1":                   CBOR,
+		"application/json;q=0, */*":                           MessagePack,
+		"text/html, application/vnd.msgpack;q=0.8, garbage;;": MessagePack,
+		"text/html": nil,
+		"application/json;q=0, application/msgpack;q=0, */*;q=0.0": nil,
+	}
+	for accept, want := range tests {
+		c, err := Negotiate(accept)
+		if c != want || (want == nil) != (err == ErrNotAcceptable) {
+			t.Errorf("%q: expected %v, got %v, %v.", accept, want, c, err)
+		}
+	}
+}
@@ -0,0 +1,498 @@
+// Package config loads the configuration of the service.
+//
+// Settings are read from, in increasing order of precedence: the defaults, a
+// YAML file, environment variables, and command line flags.
+//
+// sn - https://github.com/sn
+package config
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log/slog"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Config contains the configuration of the service
+type Config struct {
+	Server  Server  `yaml:"server"`
+	Session Session `yaml:"session"`
+	Router  Router  `yaml:"router"`
+	User    User    `yaml:"user"`
+	Tracing Tracing `yaml:"tracing"`
+}
+
+// Server contains the configuration of the HTTP server
+type Server struct {
+	Addr            string        `yaml:"addr"`
+	LogFile         string        `yaml:"log_file"`
+	LogLevel        string        `yaml:"log_level"`
+	LogFormat       string        `yaml:"log_format"`
+	ReadTimeout     time.Duration `yaml:"read_timeout"`
+	WriteTimeout    time.Duration `yaml:"write_timeout"`
+	IdleTimeout     time.Duration `yaml:"idle_timeout"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+	DrainDelay      time.Duration `yaml:"drain_delay"`
+	ReadyTimeout    time.Duration `yaml:"ready_timeout"`
+	TLS             TLS           `yaml:"tls"`
+}
+
+// TLS contains the TLS configuration of the HTTP server. TLS is enabled when
+// the certificate and key files are set.
+type TLS struct {
+	CertFile       string        `yaml:"cert_file"`
+	KeyFile        string        `yaml:"key_file"`
+	MinVersion     string        `yaml:"min_version"`
+	CipherSuites   []string      `yaml:"cipher_suites"`
+	ClientCAFile   string        `yaml:"client_ca_file"`
+	ClientAuth     string        `yaml:"client_auth"`
+	ReloadInterval time.Duration `yaml:"reload_interval"`
+	RedirectAddr   string        `yaml:"redirect_addr"`
+}
+
+// Enabled reports whether TLS is configured
+func (t TLS) Enabled() bool {
+	return t.CertFile != "" || t.KeyFile != ""
+}
+
+// Client authentication modes
+const (
+	ClientAuthNone     = "none"     // client certificates are not requested
+	ClientAuthOptional = "optional" // client certificates are verified if sent
+	ClientAuthRequire  = "require"  // client certificates are required
+)
+
+// Session contains the configuration of the session package
+type Session struct {
+	Lifetime      time.Duration `yaml:"lifetime"`
+	CleanInterval time.Duration `yaml:"clean_interval"`
+}
+
+// Router contains the configuration of the router package
+type Router struct {
+	BodyLimit         int64         `yaml:"body_limit"`
+	BatchConcurrency  int           `yaml:"batch_concurrency"`
+	MaxBatchSize      int           `yaml:"max_batch_size"`
+	IdempotencyWindow time.Duration `yaml:"idempotency_window"`
+	IdempotencyKeys   int           `yaml:"idempotency_keys"`
+	CORS              CORS          `yaml:"cors"`
+	RateLimit         RateLimit     `yaml:"rate_limit"`
+	Compression       Compression   `yaml:"compression"`
+}
+
+// Compression contains the response compression configuration of the router.
+// Responses of at least MinSize bytes are compressed with the first of
+// Encodings the client accepts. Compression is disabled when no encodings are
+// listed.
+type Compression struct {
+	Encodings []string `yaml:"encodings"`
+	MinSize   int      `yaml:"min_size"`
+}
+
+// Content codings responses can be compressed with
+const (
+	EncodingGzip = "gzip" // RFC 1952
+	EncodingZstd = "zstd" // RFC 8878
+)
+
+// RateLimit contains the rate limits of the router. Each client, identified by
+// user ID or IP address, can make Requests requests per Period. Routes, keyed
+// by method and path template without the API version, such as "POST /users",
+// can cost more than one request or have limits of their own. Rate limiting is
+// disabled when Requests is 0.
+type RateLimit struct {
+	Requests int                  `yaml:"requests"`
+	Period   time.Duration        `yaml:"period"`
+	Routes   map[string]RouteRate `yaml:"routes"`
+}
+
+// RouteRate contains the rate limit of a route. A route with Requests set is
+// limited separately from the other routes.
+type RouteRate struct {
+	Cost     int           `yaml:"cost"`
+	Requests int           `yaml:"requests"`
+	Period   time.Duration `yaml:"period"`
+}
+
+// CORS contains the cross-origin resource sharing configuration of the router.
+// CORS is disabled when no origins are allowed.
+type CORS struct {
+	AllowedOrigins   []string      `yaml:"allowed_origins"`
+	AllowedMethods   []string      `yaml:"allowed_methods"`
+	AllowedHeaders   []string      `yaml:"allowed_headers"`
+	ExposedHeaders   []string      `yaml:"exposed_headers"`
+	AllowCredentials bool          `yaml:"allow_credentials"`
+	MaxAge           time.Duration `yaml:"max_age"`
+}
+
+// User contains the configuration of the user package
+type User struct {
+	PageSize    int      `yaml:"page_size"`
+	MaxPageSize int      `yaml:"max_page_size"`
+	Reserved    []string `yaml:"reserved"`
+	Banned      []string `yaml:"banned"`
+	Admin       Admin    `yaml:"admin"`
+	Scrypt      Scrypt   `yaml:"scrypt"`
+}
+
+// Admin is a user created with the admin role when the service starts, to
+// bootstrap the first admin. No admin is created when the username is empty.
+type Admin struct {
+	Username string `yaml:"username"`
+	Email    string `yaml:"email"`
+	Password string `yaml:"password"`
+}
+
+// Tracing contains the configuration of the tracing package
+type Tracing struct {
+	Exporter    string  `yaml:"exporter"`
+	Endpoint    string  `yaml:"endpoint"`
+	Insecure    bool    `yaml:"insecure"`
+	SampleRatio float64 `yaml:"sample_ratio"`
+}
+
+// Trace exporters
+const (
+	ExporterNone   = "none"   // spans are not exported
+	ExporterStdout = "stdout" // spans are written to the log output
+	ExporterOTLP   = "otlp"   // spans are sent to an OTLP/HTTP collector
+)
+
+// Scrypt contains the parameters used to hash passwords
+type Scrypt struct {
+	N      int `yaml:"n"`
+	R      int `yaml:"r"`
+	P      int `yaml:"p"`
+	KeyLen int `yaml:"key_len"`
+}
+
+// Default returns the default configuration
+func Default() Config {
+	return Config{
+		Server: Server{
+			Addr:            ":8080",
+			LogLevel:        "info",
+			LogFormat:       "json",
+			ReadTimeout:     15 * time.Second,
+			WriteTimeout:    30 * time.Second,
+			IdleTimeout:     time.Minute,
+			ShutdownTimeout: 30 * time.Second,
+			ReadyTimeout:    5 * time.Second,
+			TLS: TLS{
+				MinVersion:     "1.2",
+				ClientAuth:     ClientAuthNone,
+				ReloadInterval: time.Minute,
+			},
+		},
+		Session: Session{
+			Lifetime:      24 * time.Hour,
+			CleanInterval: 10 * time.Minute,
+		},
+		Router: Router{
+			BodyLimit:         1048576,
+			BatchConcurrency:  8,
+			MaxBatchSize:      1000,
+			IdempotencyWindow: 24 * time.Hour,
+			IdempotencyKeys:   100000,
+			CORS: CORS{
+				AllowedMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE"},
+				AllowedHeaders: []string{"Authorization", "Content-Type", "Content-Encoding", "If-Match", "If-None-Match", "Idempotency-Key", "X-Request-ID"},
+				ExposedHeaders: []string{"ETag", "Link", "X-Request-ID", "Idempotent-Replayed", "RateLimit-Policy", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "Retry-After"},
+				MaxAge:         10 * time.Minute,
+			},
+			RateLimit: RateLimit{
+				Requests: 600,
+				Period:   time.Minute,
+				Routes: map[string]RouteRate{
+					"POST /auth":        {Requests: 10, Period: time.Minute},
+					"POST /users":       {Cost: 10},
+					"POST /users:batch": {Cost: 100},
+				},
+			},
+			Compression: Compression{
+				Encodings: []string{EncodingZstd, EncodingGzip},
+				MinSize:   1024,
+			},
+		},
+		User: User{
+			PageSize:    50,
+			MaxPageSize: 200,
+			Scrypt:      Scrypt{N: 16384, R: 8, P: 1, KeyLen: 32},
+		},
+		Tracing: Tracing{
+			Exporter:    ExporterNone,
+			SampleRatio: 1,
+		},
+	}
+}
+
+// setting is a setting that can be set by a flag or environment variable
+type setting struct {
+	name  string
+	usage string
+	value func(c *Config) interface{}
+}
+
+// settings lists the settings, by flag name. The environment variable of a
+// setting is its flag name in upper case, with dashes replaced by underscores,
+// prefixed by SN_.
+var settings = []setting{
+	{"addr", "address to listen on", func(c *Config) interface{} { return &c.Server.Addr }},
+	{"log-file", "file to write logs to instead of stdout", func(c *Config) interface{} { return &c.Server.LogFile }},
+	{"log-level", "lowest level logged (debug, info, warn, or error)", func(c *Config) interface{} { return &c.Server.LogLevel }},
+	{"log-format", "log format (json or text)", func(c *Config) interface{} { return &c.Server.LogFormat }},
+	{"read-timeout", "longest time to read a request", func(c *Config) interface{} { return &c.Server.ReadTimeout }},
+	{"write-timeout", "longest time to write a response", func(c *Config) interface{} { return &c.Server.WriteTimeout }},
+	{"idle-timeout", "longest time to keep an idle connection open", func(c *Config) interface{} { return &c.Server.IdleTimeout }},
+	{"shutdown-timeout", "longest time to wait for requests when shutting down", func(c *Config) interface{} { return &c.Server.ShutdownTimeout }},
+	{"drain-delay", "how long to fail readiness before shutting down", func(c *Config) interface{} { return &c.Server.DrainDelay }},
+	{"ready-timeout", "longest time the readiness checks can take", func(c *Config) interface{} { return &c.Server.ReadyTimeout }},
+	{"tls-cert", "TLS certificate file", func(c *Config) interface{} { return &c.Server.TLS.CertFile }},
+	{"tls-key", "TLS private key file", func(c *Config) interface{} { return &c.Server.TLS.KeyFile }},
+	{"tls-min-version", "lowest TLS version accepted (1.0, 1.1, 1.2, or 1.3)", func(c *Config) interface{} { return &c.Server.TLS.MinVersion }},
+	{"tls-cipher-suites", "comma separated TLS 1.2 cipher suites, in order of preference", func(c *Config) interface{} { return &c.Server.TLS.CipherSuites }},
+	{"tls-client-ca", "CA certificates file to verify client certificates with", func(c *Config) interface{} { return &c.Server.TLS.ClientCAFile }},
+	{"tls-client-auth", "client certificate authentication (none, optional, or require)", func(c *Config) interface{} { return &c.Server.TLS.ClientAuth }},
+	{"tls-reload-interval", "how often certificate files are checked for changes", func(c *Config) interface{} { return &c.Server.TLS.ReloadInterval }},
+	{"redirect-addr", "address to redirect HTTP to HTTPS on", func(c *Config) interface{} { return &c.Server.TLS.RedirectAddr }},
+	{"session-lifetime", "how long a session lasts", func(c *Config) interface{} { return &c.Session.Lifetime }},
+	{"session-clean-interval", "how often expired sessions are removed", func(c *Config) interface{} { return &c.Session.CleanInterval }},
+	{"body-limit", "largest request body in bytes", func(c *Config) interface{} { return &c.Router.BodyLimit }},
+	{"batch-concurrency", "batch operations run at once", func(c *Config) interface{} { return &c.Router.BatchConcurrency }},
+	{"max-batch-size", "most operations in a batch", func(c *Config) interface{} { return &c.Router.MaxBatchSize }},
+	{"idempotency-window", "how long idempotent responses are replayed", func(c *Config) interface{} { return &c.Router.IdempotencyWindow }},
+	{"idempotency-keys", "most idempotency keys remembered, dropping the oldest first", func(c *Config) interface{} { return &c.Router.IdempotencyKeys }},
+	{"rate-limit", "requests a client can make per rate limit period, or 0 to disable rate limiting", func(c *Config) interface{} { return &c.Router.RateLimit.Requests }},
+	{"rate-limit-period", "period over which client requests are limited", func(c *Config) interface{} { return &c.Router.RateLimit.Period }},
+	{"compress-encodings", "comma separated content codings responses are compressed with (zstd, gzip), in order of preference", func(c *Config) interface{} { return &c.Router.Compression.Encodings }},
+	{"compress-min-size", "smallest response body compressed, in bytes", func(c *Config) interface{} { return &c.Router.Compression.MinSize }},
+	{"cors-origins", "comma separated origins allowed cross-origin requests, such as https://*.example.com", func(c *Config) interface{} { return &c.Router.CORS.AllowedOrigins }},
+	{"cors-methods", "comma separated methods allowed in cross-origin requests", func(c *Config) interface{} { return &c.Router.CORS.AllowedMethods }},
+	{"cors-headers", "comma separated headers allowed in cross-origin requests", func(c *Config) interface{} { return &c.Router.CORS.AllowedHeaders }},
+	{"cors-expose-headers", "comma separated response headers exposed to cross-origin requests", func(c *Config) interface{} { return &c.Router.CORS.ExposedHeaders }},
+	{"cors-credentials", "allow cross-origin requests with credentials", func(c *Config) interface{} { return &c.Router.CORS.AllowCredentials }},
+	{"cors-max-age", "how long preflight responses are cached", func(c *Config) interface{} { return &c.Router.CORS.MaxAge }},
+	{"page-size", "default page size when listing users", func(c *Config) interface{} { return &c.User.PageSize }},
+	{"max-page-size", "largest page size when listing users", func(c *Config) interface{} { return &c.User.MaxPageSize }},
+	{"reserved-usernames", "comma separated usernames to reserve", func(c *Config) interface{} { return &c.User.Reserved }},
+	{"banned-usernames", "comma separated username patterns to ban", func(c *Config) interface{} { return &c.User.Banned }},
+	{"admin-username", "username of an admin created at startup", func(c *Config) interface{} { return &c.User.Admin.Username }},
+	{"admin-email", "email address of the admin created at startup", func(c *Config) interface{} { return &c.User.Admin.Email }},
+	{"admin-password", "password of the admin created at startup", func(c *Config) interface{} { return &c.User.Admin.Password }},
+	{"scrypt-n", "scrypt CPU/memory cost", func(c *Config) interface{} { return &c.User.Scrypt.N }},
+	{"scrypt-r", "scrypt block size", func(c *Config) interface{} { return &c.User.Scrypt.R }},
+	{"scrypt-p", "scrypt parallelization", func(c *Config) interface{} { return &c.User.Scrypt.P }},
+	{"scrypt-key-len", "scrypt key length in bytes", func(c *Config) interface{} { return &c.User.Scrypt.KeyLen }},
+	{"trace-exporter", "where spans are exported (none, stdout, or otlp)", func(c *Config) interface{} { return &c.Tracing.Exporter }},
+	{"trace-endpoint", "OTLP/HTTP collector address, such as localhost:4318", func(c *Config) interface{} { return &c.Tracing.Endpoint }},
+	{"trace-insecure", "send spans to the collector without TLS", func(c *Config) interface{} { return &c.Tracing.Insecure }},
+	{"trace-sample-ratio", "fraction of traces started by the service that are sampled", func(c *Config) interface{} { return &c.Tracing.SampleRatio }},
+}
+
+// envName returns the environment variable of a setting
+func envName(name string) string {
+	return "SN_" + strings.ToUpper(strings.Replace(name, "-", "_", -1))
+}
+
+// set parses a string into a setting's value
+func set(value interface{}, s string) error {
+	var err error
+	switch v := value.(type) {
+	case *string:
+		*v = s
+	case *int:
+		*v, err = strconv.Atoi(s)
+	case *int64:
+		*v, err = strconv.ParseInt(s, 10, 64)
+	case *bool:
+		*v, err = strconv.ParseBool(s)
+	case *float64:
+		*v, err = strconv.ParseFloat(s, 64)
+	case *time.Duration:
+		*v, err = time.ParseDuration(s)
+	case *[]string:
+		*v = nil
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				*v = append(*v, item)
+			}
+		}
+	default:
+		err = fmt.Errorf("Unsupported setting type %T", value)
+	}
+	return err
+}
+
+// flagValue records the value of a flag to apply after the file and
+// environment are loaded
+type flagValue struct {
+	value   string
+	set     bool
+	boolean bool
+}
+
+func (f *flagValue) String() string {
+	return f.value
+}
+
+// IsBoolFlag lets boolean flags be set without a value
+func (f *flagValue) IsBoolFlag() bool {
+	return f.boolean
+}
+
+func (f *flagValue) Set(s string) error {
+	f.value = s
+	f.set = true
+	return nil
+}
+
+// Load loads the configuration from the command line arguments (without the
+// program name), the environment, and the file given by the -config flag or
+// SN_CONFIG environment variable.
+func Load(args []string) (Config, error) {
+	c := Default()
+
+	fs := flag.NewFlagSet("service", flag.ContinueOnError)
+	path := fs.String("config", os.Getenv("SN_CONFIG"), "YAML configuration file")
+	values := map[string]*flagValue{}
+	for _, s := range settings {
+		_, boolean := s.value(&c).(*bool)
+		values[s.name] = &flagValue{boolean: boolean}
+		fs.Var(values[s.name], s.name, s.usage+" (env "+envName(s.name)+")")
+	}
+	if err := fs.Parse(args); err != nil {
+		return c, err
+	}
+
+	if *path != "" {
+		b, err := ioutil.ReadFile(*path)
+		if err != nil {
+			return c, err
+		}
+		if err := yaml.UnmarshalStrict(b, &c); err != nil {
+			return c, fmt.Errorf("Unable to parse %s: %v", *path, err)
+		}
+	}
+
+	for _, s := range settings {
+		if env, ok := os.LookupEnv(envName(s.name)); ok {
+			if err := set(s.value(&c), env); err != nil {
+				return c, fmt.Errorf("Invalid %s: %v", envName(s.name), err)
+			}
+		}
+	}
+
+	for _, s := range settings {
+		if f := values[s.name]; f.set {
+			if err := set(s.value(&c), f.value); err != nil {
+				return c, fmt.Errorf("Invalid -%s: %v", s.name, err)
+			}
+		}
+	}
+
+	return c, c.Validate()
+}
+
+// Validate checks that the configuration is usable
+func (c Config) Validate() error {
+	switch {
+	case c.Server.Addr == "":
+		return fmt.Errorf("Server address must be set.")
+	case c.Server.LogLevel == "" || new(slog.Level).UnmarshalText([]byte(c.Server.LogLevel)) != nil:
+		return fmt.Errorf("Log level must be debug, info, warn, or error.")
+	case c.Server.LogFormat != "json" && c.Server.LogFormat != "text":
+		return fmt.Errorf("Log format must be json or text.")
+	case c.Server.ReadTimeout <= 0 || c.Server.WriteTimeout <= 0 || c.Server.IdleTimeout <= 0:
+		return fmt.Errorf("Server timeouts must be positive.")
+	case c.Server.ShutdownTimeout <= 0:
+		return fmt.Errorf("Shutdown timeout must be positive.")
+	case c.Server.DrainDelay < 0:
+		return fmt.Errorf("Drain delay must not be negative.")
+	case c.Server.ReadyTimeout <= 0:
+		return fmt.Errorf("Ready timeout must be positive.")
+	case c.Server.TLS.Enabled() && (c.Server.TLS.CertFile == "" || c.Server.TLS.KeyFile == ""):
+		return fmt.Errorf("TLS certificate and key files must both be set.")
+	case !c.Server.TLS.Enabled() && c.Server.TLS.RedirectAddr != "":
+		return fmt.Errorf("Redirecting to HTTPS requires TLS.")
+	case c.Server.TLS.ClientAuth != ClientAuthNone && c.Server.TLS.ClientAuth != ClientAuthOptional && c.Server.TLS.ClientAuth != ClientAuthRequire:
+		return fmt.Errorf("TLS client auth must be none, optional, or require.")
+	case c.Server.TLS.ClientAuth != ClientAuthNone && c.Server.TLS.ClientCAFile == "":
+		return fmt.Errorf("TLS client auth requires a client CA file.")
+	case c.Server.TLS.ReloadInterval <= 0:
+		return fmt.Errorf("TLS reload interval must be positive.")
+	case c.Session.Lifetime <= 0:
+		return fmt.Errorf("Session lifetime must be positive.")
+	case c.Session.CleanInterval <= 0:
+		return fmt.Errorf("Session clean interval must be positive.")
+	case c.Router.BodyLimit <= 0:
+		return fmt.Errorf("Body limit must be positive.")
+	case c.Router.BatchConcurrency <= 0:
+		return fmt.Errorf("Batch concurrency must be positive.")
+	case c.Router.MaxBatchSize <= 0:
+		return fmt.Errorf("Max batch size must be positive.")
+	case c.Router.IdempotencyWindow <= 0:
+		return fmt.Errorf("Idempotency window must be positive.")
+	case c.Router.IdempotencyKeys <= 0:
+		return fmt.Errorf("Idempotency keys must be positive.")
+	case c.Router.RateLimit.Requests < 0:
+		return fmt.Errorf("Rate limit must not be negative.")
+	case c.Router.RateLimit.Requests > 0 && c.Router.RateLimit.Period <= 0:
+		return fmt.Errorf("Rate limit period must be positive.")
+	case c.Router.Compression.MinSize < 0:
+		return fmt.Errorf("Compression min size must not be negative.")
+	case c.Router.CORS.MaxAge < 0:
+		return fmt.Errorf("CORS max age must not be negative.")
+	case c.Tracing.Exporter != ExporterNone && c.Tracing.Exporter != ExporterStdout && c.Tracing.Exporter != ExporterOTLP:
+		return fmt.Errorf("Trace exporter must be none, stdout, or otlp.")
+	case c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1:
+		return fmt.Errorf("Trace sample ratio must be between 0 and 1.")
+	case c.User.PageSize <= 0 || c.User.PageSize > c.User.MaxPageSize:
+		return fmt.Errorf("Page size must be positive and at most the max page size.")
+	case c.User.Scrypt.N <= 1 || c.User.Scrypt.N&(c.User.Scrypt.N-1) != 0:
+		return fmt.Errorf("Scrypt N must be a power of two greater than one.")
+	case c.User.Scrypt.R <= 0 || c.User.Scrypt.P <= 0 || c.User.Scrypt.R*c.User.Scrypt.P >= 1<<30:
+		return fmt.Errorf("Scrypt r and p must be positive, and r * p less than 2^30.")
+	case c.User.Scrypt.KeyLen < 16:
+		return fmt.Errorf("Scrypt key length must be at least 16 bytes.")
+	case c.User.Admin != Admin{} && (c.User.Admin.Username == "" || c.User.Admin.Email == "" || c.User.Admin.Password == ""):
+		return fmt.Errorf("Admin username, email, and password must all be set.")
+	}
+	for _, encoding := range c.Router.Compression.Encodings {
+		if encoding != EncodingZstd && encoding != EncodingGzip {
+			return fmt.Errorf("Compression encoding %q must be zstd or gzip.", encoding)
+		}
+	}
+	for _, origin := range c.Router.CORS.AllowedOrigins {
+		if _, err := path.Match(origin, ""); err != nil {
+			return fmt.Errorf("Invalid CORS origin %q: %v", origin, err)
+		}
+	}
+	for route, rate := range c.Router.RateLimit.Routes {
+		limit := c.Router.RateLimit.Requests
+		if rate.Requests > 0 {
+			limit = rate.Requests
+		}
+		switch {
+		case len(strings.SplitN(route, " /", 2)) != 2:
+			return fmt.Errorf("Rate limited route %q must be a method and path, such as \"POST /users\".", route)
+		case rate.Cost < 0 || rate.Requests < 0 || rate.Period < 0:
+			return fmt.Errorf("Rate limit of %s must not be negative.", route)
+		case rate.Cost > limit && limit > 0:
+			return fmt.Errorf("Rate limit cost of %s must not exceed its limit.", route)
+		}
+	}
+	for _, pattern := range c.User.Banned {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("Invalid banned username pattern %q: %v", pattern, err)
+		}
+	}
+	return nil
+}
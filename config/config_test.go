@@ -0,0 +1,148 @@
+// Package config loads the configuration of the service.
+//
+// sn - https://github.com/sn
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDefault(t *testing.T) {
+	if err := Default().Validate(); err != nil {
+		t.Error(err)
+	}
+	c, err := Load(nil)
+	if err != nil {
+		t.Error(err)
+	}
+	if !reflect.DeepEqual(c, Default()) {
+		t.Error("Expected defaults without a file, environment, or flags.")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "config.yaml")
+	yaml := `
+server:
+  addr: ":8000"
+  log_file: /var/log/sn.log
+session:
+  lifetime: 1h
+router:
+  rate_limit:
+    routes:
+      GET /users: {cost: 2}
+user:
+  reserved: [sn, staff]
+  admin:
+    username: alex
+    email: alex@example.com
+  scrypt:
+    n: 1024
+`
+	if err := ioutil.WriteFile(path, []byte(yaml), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("SN_CONFIG", path)
+	t.Setenv("SN_ADDR", ":9000")
+	t.Setenv("SN_SESSION_LIFETIME", "2h")
+	t.Setenv("SN_ADMIN_PASSWORD", "1@E4s67890")
+	c, err := Load([]string{"-addr", ":9090", "-banned-usernames", "foo, bar", "-trace-insecure", "-trace-sample-ratio", "0.5", "-cors-origins", "https://*.example.com", "-compress-encodings", "gzip"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.Server.Addr != ":9090" {
+		t.Error("Expected flag to override environment.")
+	}
+	if c.Session.Lifetime != 2*time.Hour {
+		t.Error("Expected environment to override file.")
+	}
+	if c.Server.LogFile != "/var/log/sn.log" || c.User.Scrypt.N != 1024 {
+		t.Error("Expected file to override defaults.")
+	}
+	if !c.Tracing.Insecure || c.Tracing.SampleRatio != 0.5 {
+		t.Error("Expected boolean and float flags to be set.")
+	}
+	if c.User.Scrypt.R != 8 || c.Router.BodyLimit != 1048576 {
+		t.Error("Expected defaults for unset values.")
+	}
+	if !reflect.DeepEqual(c.User.Reserved, []string{"sn", "staff"}) || !reflect.DeepEqual(c.User.Banned, []string{"foo", "bar"}) {
+		t.Error("Expected lists to be loaded.")
+	}
+	if c.User.Admin != (Admin{"alex", "alex@example.com", "1@E4s67890"}) {
+		t.Error("Expected the admin to be loaded.")
+	}
+	if !reflect.DeepEqual(c.Router.CORS.AllowedOrigins, []string{"https://*.example.com"}) || c.Router.CORS.MaxAge != 10*time.Minute {
+		t.Error("Expected CORS origins to be set.")
+	}
+	if !reflect.DeepEqual(c.Router.Compression.Encodings, []string{"gzip"}) || c.Router.Compression.MinSize != 1024 {
+		t.Error("Expected compression encodings to be set.")
+	}
+	if c.Router.RateLimit.Routes["GET /users"].Cost != 2 || c.Router.RateLimit.Routes["POST /users"].Cost != 10 {
+		t.Error("Expected route rate limits to be merged with the defaults.")
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "config.yaml")
+	if err := ioutil.WriteFile(path, []byte("server:\n  port: 80\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	args := [][]string{
+		{"-unknown"},
+		{"-config", filepath.Join(dir, "missing.yaml")},
+		{"-config", path},
+		{"-session-lifetime", "forever"},
+		{"-session-lifetime", "0s"},
+		{"-scrypt-n", "1000"},
+		{"-page-size", "500"},
+		{"-banned-usernames", "("},
+		{"-admin-username", "root"},
+		{"-drain-delay", "-1s"},
+		{"-trace-exporter", "jaeger"},
+		{"-trace-sample-ratio", "2"},
+		{"-trace-insecure=maybe"},
+		{"-log-level", "verbose"},
+		{"-log-format", "xml"},
+		{"-tls-cert", "cert.pem"},
+		{"-redirect-addr", ":80"},
+		{"-tls-client-auth", "always"},
+		{"-tls-client-auth", "require"},
+		{"-cors-origins", "https://[example.com"},
+		{"-cors-max-age", "-1m"},
+		{"-idempotency-keys", "0"},
+		{"-rate-limit", "-1"},
+		{"-rate-limit-period", "0s"},
+		{"-rate-limit", "5"},
+		{"-compress-encodings", "br"},
+		{"-compress-min-size", "-1"},
+	}
+	for _, a := range args {
+		if _, err := Load(a); err == nil {
+			t.Errorf("%v: expected error.", a)
+		}
+	}
+
+	t.Setenv("SN_BODY_LIMIT", "lots")
+	if _, err := Load(nil); err == nil {
+		t.Error("Expected invalid environment variable to fail.")
+	}
+}
@@ -0,0 +1,155 @@
+// Package health reports whether the service is alive and ready to serve.
+//
+// sn - https://github.com/sn
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Check checks a dependency of the service, returning an error when it is
+// not ready
+type Check func(ctx context.Context) error
+
+// Timeout is the longest time the readiness checks can take
+var Timeout = 5 * time.Second
+
+// Statuses of the service and its checks
+const (
+	StatusOK           = "ok"
+	StatusFailing      = "failing"
+	StatusShuttingDown = "shutting down"
+)
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+var (
+	mu       sync.RWMutex
+	checks   []namedCheck
+	draining int32
+)
+
+// Result is the result of a readiness check
+type Result struct {
+	Name       string  `json:"name"`
+	Status     string  `json:"status"`
+	DurationMS float64 `json:"duration_ms"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// Report is the readiness of the service
+type Report struct {
+	Status string   `json:"status"`
+	Checks []Result `json:"checks"`
+}
+
+// Register adds a readiness check
+func Register(name string, check Check) {
+	mu.Lock()
+	defer mu.Unlock()
+	checks = append(checks, namedCheck{name, check})
+}
+
+// RLocker is a lock that can be held for reading, such as a *sync.RWMutex
+type RLocker interface {
+	RLock()
+	RUnlock()
+}
+
+// ProbeLock checks that a lock guarding a store can be held for reading
+// before the context is done, for the readiness checks of in-memory stores
+func ProbeLock(ctx context.Context, l RLocker, store string) error {
+	done := make(chan struct{})
+	go func() {
+		l.RLock()
+		l.RUnlock()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%s is unavailable: %v", store, ctx.Err())
+	}
+}
+
+// Drain marks the service as shutting down, failing readiness so that no new
+// requests are routed to it
+func Drain() {
+	atomic.StoreInt32(&draining, 1)
+}
+
+// Draining reports whether the service is shutting down
+func Draining() bool {
+	return atomic.LoadInt32(&draining) == 1
+}
+
+// Run runs the readiness checks concurrently, within Timeout, and reports
+// their results in the order they were registered
+func Run(ctx context.Context) Report {
+	mu.RLock()
+	registered := append([]namedCheck(nil), checks...)
+	mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+	report := Report{Status: StatusOK, Checks: make([]Result, len(registered))}
+	var wg sync.WaitGroup
+	for i, c := range registered {
+		wg.Add(1)
+		go func(i int, c namedCheck) {
+			defer wg.Done()
+			start := time.Now()
+			err := c.check(ctx)
+			result := Result{Name: c.name, Status: StatusOK, DurationMS: time.Since(start).Seconds() * 1000}
+			if err != nil {
+				result.Status = StatusFailing
+				result.Error = err.Error()
+			}
+			report.Checks[i] = result
+		}(i, c)
+	}
+	wg.Wait()
+
+	for _, result := range report.Checks {
+		if result.Status != StatusOK {
+			report.Status = StatusFailing
+		}
+	}
+	if Draining() {
+		report.Status = StatusShuttingDown
+	}
+	return report
+}
+
+// Live handles GET /healthz, responding with 200 while the process is alive
+var Live = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"status": StatusOK})
+})
+
+// Ready handles GET /readyz, responding with the report of the readiness
+// checks, with 200 when they pass and 503 when any fails or the service is
+// shutting down
+var Ready = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	report := Run(r.Context())
+	status := http.StatusOK
+	if report.Status != StatusOK {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(report)
+})
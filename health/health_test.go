@@ -0,0 +1,102 @@
+// Package health reports whether the service is alive and ready to serve.
+//
+// sn - https://github.com/sn
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// ready runs the readiness handler and returns its status and report
+func ready(t *testing.T) (int, Report) {
+	rec := httptest.NewRecorder()
+	Ready.ServeHTTP(rec, httptest.NewRequest("GET", "/readyz", nil))
+	var report Report
+	if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+		t.Fatal(err)
+	}
+	return rec.Code, report
+}
+
+func TestLive(t *testing.T) {
+	rec := httptest.NewRecorder()
+	Live.ServeHTTP(rec, httptest.NewRequest("GET", "/healthz", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d.", rec.Code)
+	}
+}
+
+func TestReady(t *testing.T) {
+	defer func() {
+		checks = nil
+		atomic.StoreInt32(&draining, 0)
+	}()
+
+	Register("store", func(ctx context.Context) error { return nil })
+	status, report := ready(t)
+	if status != http.StatusOK || report.Status != StatusOK || len(report.Checks) != 1 || report.Checks[0].Name != "store" {
+		t.Errorf("Expected passing checks to be ready, got %d %+v.", status, report)
+	}
+
+	failing := true
+	Register("mailer", func(ctx context.Context) error {
+		if failing {
+			return fmt.Errorf("Mailer is not configured.")
+		}
+		return nil
+	})
+	status, report = ready(t)
+	if status != http.StatusServiceUnavailable || report.Status != StatusFailing {
+		t.Errorf("Expected a failing check not to be ready, got %d %+v.", status, report)
+	}
+	if result := report.Checks[1]; result.Status != StatusFailing || result.Error != "Mailer is not configured." {
+		t.Errorf("Expected the failing check to be reported, got %+v.", result)
+	}
+
+	failing = false
+	Drain()
+	status, report = ready(t)
+	if status != http.StatusServiceUnavailable || report.Status != StatusShuttingDown {
+		t.Errorf("Expected not to be ready while draining, got %d %+v.", status, report)
+	}
+}
+
+func TestRunTimeout(t *testing.T) {
+	defer func(timeout time.Duration) {
+		checks = nil
+		Timeout = timeout
+	}(Timeout)
+
+	Timeout = 10 * time.Millisecond
+	Register("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	report := Run(context.Background())
+	if report.Status != StatusFailing || report.Checks[0].Error != context.DeadlineExceeded.Error() {
+		t.Errorf("Expected a slow check to time out, got %+v.", report)
+	}
+}
+
+func TestProbeLock(t *testing.T) {
+	var mu sync.RWMutex
+	if err := ProbeLock(context.Background(), &mu, "Store"); err != nil {
+		t.Error(err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := ProbeLock(ctx, &mu, "Store"); err == nil || !strings.HasPrefix(err.Error(), "Store is unavailable") {
+		t.Errorf("Expected a locked store to be unavailable, got %v.", err)
+	}
+}
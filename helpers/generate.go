@@ -4,29 +4,45 @@
 package helpers
 
 import (
-	"crypto/rand"
+	"context"
 	"crypto/sha1"
 	"encoding/hex"
-	"fmt"
 	"log"
+	"time"
 
+	"github.com/sn/service/metrics"
+	"github.com/sn/service/tracing"
 	"github.com/sn/service/types"
 	"golang.org/x/crypto/scrypt"
 )
 
-// GenerateUUID generates a universally unique identifier
+// GenerateUUID generates a random (version 4) universally unique identifier
 func GenerateUUID() types.UUID {
-	b := make([]byte, 16)
-	_, err := rand.Read(b)
+	id, err := types.NewV4()
 	if err != nil {
 		log.Fatal(err)
 	}
-	return types.UUID(fmt.Sprintf("%X-%X-%X-%X-%X", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]))
+	return id
 }
 
+// ScryptParams are the parameters used to hash passwords with scrypt
+type ScryptParams struct {
+	N      int
+	R      int
+	P      int
+	KeyLen int
+}
+
+// Scrypt contains the parameters used by GeneratePasswordHash
+var Scrypt = ScryptParams{N: 16384, R: 8, P: 1, KeyLen: 32}
+
 // GeneratePasswordHash generates a password hash using scrypt
-func GeneratePasswordHash(password string) string {
-	hash, err := scrypt.Key([]byte(password), []byte("!@)#(!@#"), 16384, 8, 1, 32)
+func GeneratePasswordHash(ctx context.Context, password string) string {
+	_, span := tracing.Start(ctx, "helpers.GeneratePasswordHash")
+	defer span.End()
+	start := time.Now()
+	defer func() { metrics.PasswordHashDuration.Observe(time.Since(start).Seconds()) }()
+	hash, err := scrypt.Key([]byte(password), []byte("!@)#(!@#"), Scrypt.N, Scrypt.R, Scrypt.P, Scrypt.KeyLen)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -0,0 +1,70 @@
+// Package logging configures structured logging and carries request loggers
+// in contexts.
+//
+// sn - https://github.com/sn
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := New(&buf, "warn", FormatJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Info("hidden")
+	l.Warn("shown", "password", "1@E4s67890", "headers", Headers(http.Header{
+		"Authorization": {"secret"},
+		"Accept":        {"text/plain", "application/json"},
+	}))
+
+	var entry struct {
+		Msg      string
+		Password string
+		Headers  map[string]string
+	}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("Expected a single JSON entry, got %q: %v", buf.String(), err)
+	}
+	if entry.Msg != "shown" {
+		t.Errorf("Expected entries below the level to be dropped, got %s.", entry.Msg)
+	}
+	if entry.Password != Redacted || entry.Headers["Authorization"] != Redacted {
+		t.Error("Expected password and Authorization to be redacted.")
+	}
+	if entry.Headers["Accept"] != "text/plain, application/json" {
+		t.Errorf("Expected headers to be logged, got %v.", entry.Headers)
+	}
+	if strings.Contains(buf.String(), "secret") || strings.Contains(buf.String(), "1@E4s67890") {
+		t.Error("Expected secrets not to be logged.")
+	}
+
+	for _, args := range [][2]string{{"verbose", FormatJSON}, {"info", "xml"}} {
+		if _, err := New(&buf, args[0], args[1]); err == nil {
+			t.Errorf("%v: expected error.", args)
+		}
+	}
+}
+
+func TestContext(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := New(&buf, "info", FormatText)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := NewContext(context.Background(), l.With("request_id", "abc"))
+	FromContext(ctx).Info("hello")
+	if !strings.Contains(buf.String(), "request_id=abc") {
+		t.Errorf("Expected the context logger, got %q.", buf.String())
+	}
+	if FromContext(context.Background()) == nil {
+		t.Error("Expected the default logger without one in the context.")
+	}
+}
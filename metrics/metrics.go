@@ -0,0 +1,86 @@
+// Package metrics collects the Prometheus metrics of the service.
+//
+// sn - https://github.com/sn
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Namespace prefixes the names of the service's metrics
+const Namespace = "sn"
+
+// Registry contains the metrics of the service and of the Go runtime
+var Registry = prometheus.NewRegistry()
+
+var (
+	// Requests counts HTTP requests by method, route template, and status
+	Requests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, route, and status.",
+	}, []string{"method", "route", "status"})
+
+	// RequestDuration observes HTTP request latencies by method, route
+	// template, and status
+	RequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latencies by method, route, and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// AuthAttempts counts authentication attempts by result, success or
+	// failure
+	AuthAttempts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "auth_attempts_total",
+		Help:      "Authentication attempts by result.",
+	}, []string{"result"})
+
+	// RateLimited counts requests refused by rate limiting, by route template
+	RateLimited = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "rate_limited_requests_total",
+		Help:      "Requests refused by rate limiting, by route.",
+	}, []string{"route"})
+
+	// PasswordHashDuration observes how long hashing a password takes
+	PasswordHashDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Name:      "password_hash_duration_seconds",
+		Help:      "Time spent hashing passwords.",
+		Buckets:   prometheus.ExponentialBuckets(0.001, 2, 12),
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		Requests,
+		RequestDuration,
+		AuthAttempts,
+		RateLimited,
+		PasswordHashDuration,
+	)
+}
+
+// GaugeFunc registers a gauge whose value is read from fn when metrics are
+// collected
+func GaugeFunc(name, help string, fn func() float64) {
+	Registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: Namespace,
+		Name:      name,
+		Help:      help,
+	}, fn))
+}
+
+// Handler serves the metrics in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,34 @@
+// Package metrics collects the Prometheus metrics of the service.
+//
+// sn - https://github.com/sn
+package metrics
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	GaugeFunc("test_value", "A value for testing.", func() float64 { return 42 })
+	Requests.WithLabelValues("GET", "/users", "200").Inc()
+	PasswordHashDuration.Observe(0.05)
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, err := ioutil.ReadAll(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		"sn_test_value 42",
+		`sn_http_requests_total{method="GET",route="/users",status="200"} 1`,
+		"sn_password_hash_duration_seconds_count 1",
+		"go_goroutines",
+	} {
+		if !strings.Contains(string(body), s) {
+			t.Errorf("Expected metrics to contain %q.", s)
+		}
+	}
+}
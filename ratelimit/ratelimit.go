@@ -0,0 +1,124 @@
+// Package ratelimit limits how often clients can make requests, using token
+// buckets.
+//
+// sn - https://github.com/sn
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Limit is a token bucket holding up to Requests tokens, refilled at Requests
+// tokens per Period
+type Limit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// rate returns the tokens added per second
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Period.Seconds()
+}
+
+// Result is the outcome of taking tokens from a bucket
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration // until the bucket is full
+	RetryAfter time.Duration // until the tokens can be taken, if not allowed
+}
+
+// Store takes tokens from the buckets of clients. A Store shared between
+// instances of the service limits clients across all of them.
+type Store interface {
+	Take(ctx context.Context, key string, cost int, limit Limit) (Result, error)
+}
+
+// bucket is the state of a token bucket
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	limit   Limit
+}
+
+// refill adds the tokens accumulated since the bucket was updated
+func (b *bucket) refill(now time.Time) {
+	b.tokens = math.Min(float64(b.limit.Requests), b.tokens+now.Sub(b.updated).Seconds()*b.limit.rate())
+	b.updated = now
+}
+
+// full reports whether the bucket has refilled by a time
+func (b *bucket) full(now time.Time) bool {
+	return b.tokens+now.Sub(b.updated).Seconds()*b.limit.rate() >= float64(b.limit.Requests)
+}
+
+// Memory is a Store keeping buckets in memory, limiting clients per instance
+type Memory struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	cleaned time.Time
+	now     func() time.Time
+}
+
+// CleanInterval is how often full buckets are removed from a Memory store
+var CleanInterval = time.Minute
+
+// NewMemory returns an empty in-memory store
+func NewMemory() *Memory {
+	return &Memory{buckets: map[string]*bucket{}, cleaned: time.Now(), now: time.Now}
+}
+
+// Take takes cost tokens from the bucket of a key if it holds enough. A new
+// bucket starts full.
+func (m *Memory) Take(ctx context.Context, key string, cost int, limit Limit) (Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	if now.Sub(m.cleaned) >= CleanInterval {
+		m.clean(now)
+	}
+
+	b, ok := m.buckets[key]
+	if !ok || b.limit != limit {
+		b = &bucket{tokens: float64(limit.Requests), updated: now, limit: limit}
+		m.buckets[key] = b
+	}
+	b.refill(now)
+
+	res := Result{Limit: limit.Requests}
+	if b.tokens >= float64(cost) {
+		b.tokens -= float64(cost)
+		res.Allowed = true
+	} else {
+		res.RetryAfter = seconds((float64(cost) - b.tokens) / limit.rate())
+	}
+	res.Remaining = int(b.tokens)
+	res.Reset = seconds((float64(limit.Requests) - b.tokens) / limit.rate())
+	return res, nil
+}
+
+// clean removes the buckets that have refilled, which behave like new ones
+func (m *Memory) clean(now time.Time) {
+	for key, b := range m.buckets {
+		if b.full(now) {
+			delete(m.buckets, key)
+		}
+	}
+	m.cleaned = now
+}
+
+// Len returns the number of buckets kept
+func (m *Memory) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.buckets)
+}
+
+// seconds converts seconds to a duration
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
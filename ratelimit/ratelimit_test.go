@@ -0,0 +1,58 @@
+// Package ratelimit limits how often clients can make requests, using token
+// buckets.
+//
+// sn - https://github.com/sn
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemory(t *testing.T) {
+	now := time.Now()
+	m := NewMemory()
+	m.now = func() time.Time { return now }
+	limit := Limit{Requests: 10, Period: 10 * time.Second}
+
+	for i := 9; i >= 0; i-- {
+		res, err := m.Take(context.Background(), "alex", 1, limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !res.Allowed || res.Remaining != i || res.Limit != 10 {
+			t.Errorf("Expected request to be allowed with %d remaining, got %+v.", i, res)
+		}
+	}
+	res, _ := m.Take(context.Background(), "alex", 1, limit)
+	if res.Allowed || res.RetryAfter != time.Second || res.Reset != 10*time.Second {
+		t.Errorf("Expected an empty bucket to refuse for a second, got %+v.", res)
+	}
+	if res, _ := m.Take(context.Background(), "blake", 1, limit); !res.Allowed {
+		t.Error("Expected buckets to be per key.")
+	}
+
+	now = now.Add(3 * time.Second)
+	if res, _ := m.Take(context.Background(), "alex", 3, limit); !res.Allowed || res.Remaining != 0 {
+		t.Errorf("Expected the bucket to refill, got %+v.", res)
+	}
+	if res, _ := m.Take(context.Background(), "alex", 11, limit); res.Allowed {
+		t.Error("Expected a cost above the limit to be refused.")
+	}
+}
+
+func TestMemoryClean(t *testing.T) {
+	now := time.Now()
+	m := NewMemory()
+	m.now = func() time.Time { return now }
+	limit := Limit{Requests: 10, Period: 10 * time.Minute}
+
+	m.Take(context.Background(), "alex", 1, limit)
+	m.Take(context.Background(), "blake", 10, limit)
+	now = now.Add(CleanInterval + time.Second)
+	m.Take(context.Background(), "corey", 1, limit)
+	if m.Len() != 2 {
+		t.Errorf("Expected refilled buckets to be removed, got %d buckets.", m.Len())
+	}
+}
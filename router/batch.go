@@ -0,0 +1,132 @@
+// Package router contains endpoint information for the service.
+//
+// sn - https://github.com/sn
+package router
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/sn/service/logging"
+	"github.com/sn/service/types"
+	"github.com/sn/service/user"
+)
+
+var (
+	// BatchConcurrency is how many operations of a batch run at once
+	BatchConcurrency = 8
+
+	// MaxBatchSize is the most operations a batch can contain
+	MaxBatchSize = 1000
+)
+
+// batchOperation is an operation in a batch request. Create operations use
+// the user fields, and the other operations use the ID.
+type batchOperation struct {
+	Op string `json:"op"`
+	ID string `json:"id"`
+	userInput
+}
+
+// batchResult is the result of an operation in a batch request
+type batchResult struct {
+	Status int        `json:"status"`
+	User   *user.User `json:"user,omitempty"`
+	Error  string     `json:"error,omitempty"`
+}
+
+// UserBatch handles POST /users:batch
+//
+// The body contains a list of operations:
+//
+//	{"operations": [
+//		{"op": "create", "username": "alex", "password": "...", "email": "..."},
+//		{"op": "deactivate", "id": "..."},
+//		{"op": "delete", "id": "..."}
+//	]}
+//
+// Operations run concurrently, and the response contains a result for each
+// operation, in order, with the status code the operation would have had on
+// its own. A failing operation does not affect the others. Only admins can
+// run batches.
+var UserBatch = handler(func(w http.ResponseWriter, r *http.Request) error {
+	var input struct {
+		Operations []batchOperation `json:"operations"`
+	}
+	if _, err := authorizeAdmin(r); err != nil {
+		return err
+	}
+	c, err := requestCodec(r)
+	if err != nil {
+		return err
+	}
+	body, err := readBody(r)
+	if err != nil {
+		return err
+	}
+	if err := c.Unmarshal(body, &input); err != nil {
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, "Bad Request")
+		return nil
+	}
+	if len(input.Operations) > MaxBatchSize {
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		fmt.Fprintf(w, "Batch must contain at most %d operations.", MaxBatchSize)
+		return nil
+	}
+
+	results := make([]batchResult, len(input.Operations))
+	sem := make(chan struct{}, BatchConcurrency)
+	var wg sync.WaitGroup
+	for i, op := range input.Operations {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, op batchOperation) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = runBatchOperation(r.Context(), op)
+		}(i, op)
+	}
+	wg.Wait()
+
+	return respond(w, r, http.StatusOK, map[string][]batchResult{"results": results})
+})
+
+// runBatchOperation runs an operation of a batch request
+func runBatchOperation(ctx context.Context, op batchOperation) batchResult {
+	switch op.Op {
+	case "create":
+		u, err := createUser(ctx, op.userInput)
+		if serr, ok := err.(*statusError); ok {
+			return batchResult{Status: serr.status, Error: serr.message}
+		}
+		if err != nil {
+			logging.FromContext(ctx).Error("batch operation failed", "error", err)
+			return batchResult{Status: http.StatusInternalServerError, Error: "Internal Server Error"}
+		}
+		return batchResult{Status: http.StatusCreated, User: &u}
+	case "deactivate", "delete":
+	default:
+		return batchResult{Status: http.StatusBadRequest, Error: fmt.Sprintf("Unsupported op %q.", op.Op)}
+	}
+
+	id, err := types.Parse(op.ID)
+	if err != nil {
+		return batchResult{Status: http.StatusBadRequest, Error: "Invalid ID."}
+	}
+	if op.Op == "deactivate" {
+		u := user.Deactivate(ctx, id)
+		if len(u.ID) == 0 {
+			return batchResult{Status: http.StatusNotFound, Error: "Not found"}
+		}
+		return batchResult{Status: http.StatusOK, User: &u}
+	}
+	if err := user.Delete(ctx, id); err != nil {
+		return batchResult{Status: http.StatusNotFound, Error: "Not found"}
+	}
+	return batchResult{Status: http.StatusNoContent}
+}
@@ -0,0 +1,111 @@
+// Package router contains endpoint information for the service.
+//
+// sn - https://github.com/sn
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/sn/service/helpers"
+	"github.com/sn/service/user"
+)
+
+func TestUserBatch(t *testing.T) {
+	var ops []string
+	for i := 0; i < 20; i++ {
+		ops = append(ops, fmt.Sprintf(`{"op":"create","username":"batch%d","password":"1@E4s67890","email":"batch%d@example.com"}`, i, i))
+	}
+	ops = append(ops,
+		`{"op":"create","username":"batchy","password":"1@E4s67890","email":"alex@example.com"}`,
+		`{"op":"create","username":"batchx","password":"short","email":"batchx@example.com"}`,
+		`{"op":"deactivate","id":"`+string(helpers.GenerateUUID())+`"}`,
+		`{"op":"delete","id":"garbage"}`,
+		`{"op":"rename"}`,
+	)
+
+	var output struct {
+		Results []struct {
+			Status int
+			User   *user.User
+			Error  string
+		}
+	}
+	token := newAdmin(t, "batcher")
+	post := func(ops []string) {
+		req, _ := http.NewRequest("POST", server.URL+"/users:batch", strings.NewReader(`{"operations":[`+strings.Join(ops, ",")+`]}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", token)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Expected 200, got %d.", resp.StatusCode)
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&output); err != nil {
+			t.Fatal(err)
+		}
+		if len(output.Results) != len(ops) {
+			t.Fatalf("Expected %d results, got %d.", len(ops), len(output.Results))
+		}
+	}
+
+	post(ops)
+	statuses := []int{http.StatusConflict, http.StatusBadRequest, http.StatusNotFound, http.StatusBadRequest, http.StatusBadRequest}
+	for i, result := range output.Results {
+		if i < 20 {
+			if result.Status != http.StatusCreated || result.User == nil {
+				t.Errorf("Operation %d: expected user to be created, got %d.", i, result.Status)
+			}
+			continue
+		}
+		if result.Status != statuses[i-20] || result.Error == "" {
+			t.Errorf("Operation %d: expected status %d, got %d.", i, statuses[i-20], result.Status)
+		}
+	}
+	first, second := output.Results[0].User.ID, output.Results[1].User.ID
+	post([]string{
+		`{"op":"deactivate","id":"` + string(first) + `"}`,
+		`{"op":"delete","id":"` + string(second) + `"}`,
+	})
+	if output.Results[0].Status != http.StatusOK || !user.FindByID(context.Background(), first).Deactivated {
+		t.Error("User was not deactivated.")
+	}
+	if output.Results[1].Status != http.StatusNoContent || len(user.FindByID(context.Background(), second).ID) != 0 {
+		t.Error("User was not deleted.")
+	}
+	if _, err := getAuthToken(user.User{ID: first, Password: "1@E4s67890"}); err == nil {
+		t.Error("Deactivated user should not authenticate.")
+	}
+}
+
+func TestUserBatchRequiresAdmin(t *testing.T) {
+	blake := user.FindByUsername(context.Background(), "blake")
+	token, err := getAuthToken(user.User{ID: blake.ID, Password: "1@E4s67890"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := `{"operations":[{"op":"create","username":"intruder","password":"1@E4s67890","email":"intruder@example.com"}]}`
+	for auth, status := range map[string]int{"": http.StatusUnauthorized, token: http.StatusForbidden} {
+		req, _ := http.NewRequest("POST", server.URL+"/v1/users:batch", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", auth)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != status {
+			t.Errorf("Expected %d, got %d.", status, resp.StatusCode)
+		}
+	}
+	if u := user.FindByUsername(context.Background(), "intruder"); len(u.ID) != 0 {
+		t.Error("Expected no user to be created.")
+	}
+}
@@ -0,0 +1,66 @@
+// Package router contains endpoint information for the service.
+//
+// sn - https://github.com/sn
+package router
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/sn/service/codec"
+)
+
+// codecKey is the context key of the response codec
+const codecKey contextKey = iota + 1
+
+// Negotiate picks the codec of the response from the Accept header, refusing
+// the request with 406 if the service cannot produce any acceptable media type
+func Negotiate(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept")
+		c, err := codec.Negotiate(r.Header.Get("Accept"))
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+			w.WriteHeader(http.StatusNotAcceptable)
+			fmt.Fprint(w, "Not Acceptable")
+			return
+		}
+		h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), codecKey, c)))
+	})
+}
+
+// responseCodec returns the codec negotiated for a request, or JSON
+func responseCodec(r *http.Request) codec.Codec {
+	if c, ok := r.Context().Value(codecKey).(codec.Codec); ok {
+		return c
+	}
+	return codec.JSON
+}
+
+// requestCodec returns the codec of the Content-Type of a request. A
+// Content-Type without a codec is a *statusError with 415.
+func requestCodec(r *http.Request) (codec.Codec, error) {
+	c, err := codec.ForContentType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, &statusError{http.StatusUnsupportedMediaType, "Unsupported media type."}
+	}
+	return c, nil
+}
+
+// respond writes a status and a value encoded in the negotiated codec
+func respond(w http.ResponseWriter, r *http.Request, status int, v interface{}) error {
+	c := responseCodec(r)
+	b, err := c.Marshal(v)
+	if err != nil {
+		return err
+	}
+	contentType := c.MediaType()
+	if c == codec.JSON {
+		contentType += "; charset=UTF-8"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
+	_, err = w.Write(b)
+	return err
+}
@@ -0,0 +1,105 @@
+// Package router contains endpoint information for the service.
+//
+// sn - https://github.com/sn
+package router
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/sn/service/codec"
+	"github.com/sn/service/user"
+)
+
+func TestCodecs(t *testing.T) {
+	for i, c := range []codec.Codec{codec.MessagePack, codec.CBOR} {
+		username := []string{"finley", "gray"}[i]
+		body, err := c.Marshal(userInput{Username: username, Password: "1@E4s67890", Address: username + "@example.com"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, _ := http.NewRequest("POST", server.URL+"/v1/users", bytes.NewReader(body))
+		req.Header.Set("Content-Type", c.MediaType())
+		req.Header.Set("Accept", c.MediaType())
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ = ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusCreated || resp.Header.Get("Content-Type") != c.MediaType() {
+			t.Fatalf("%s: expected 201 in %[1]s, got %d in %s: %s", c.MediaType(), resp.StatusCode, resp.Header.Get("Content-Type"), body)
+		}
+		var created user.User
+		if err := c.Unmarshal(body, &created); err != nil || created.Username != username {
+			t.Errorf("%s: expected the created user, got %+v, %v.", c.MediaType(), created, err)
+		}
+
+		req, _ = http.NewRequest("GET", server.URL+"/v1/users/"+string(created.ID), nil)
+		req.Header.Set("Accept", "application/json;q=0.5, "+c.MediaType())
+		resp, err = client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ = ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		var shown user.User
+		if err := c.Unmarshal(body, &shown); err != nil || shown.ID != created.ID || !shown.Created.Equal(created.Created) {
+			t.Errorf("%s: expected the user, got %+v, %v.", c.MediaType(), shown, err)
+		}
+		if !strings.Contains(resp.Header.Get("Vary"), "Accept") {
+			t.Errorf("%s: expected Vary: Accept, got %q.", c.MediaType(), resp.Header.Get("Vary"))
+		}
+
+		// bodies are validated against the document like JSON bodies
+		body, _ = c.Marshal(map[string]string{"username": username, "password": "1@E4s67890"})
+		req, _ = http.NewRequest("POST", server.URL+"/v1/users", bytes.NewReader(body))
+		req.Header.Set("Content-Type", c.MediaType())
+		resp, err = client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ = ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest || string(body) != "email is required." {
+			t.Errorf("%s: expected 400, got %d %q.", c.MediaType(), resp.StatusCode, body)
+		}
+	}
+}
+
+func TestUnsupportedMediaTypes(t *testing.T) {
+	tests := []struct {
+		method      string
+		path        string
+		contentType string
+		accept      string
+		status      int
+	}{
+		{"GET", "/v1/users", "", "text/html", http.StatusNotAcceptable},
+		{"GET", "/users", "", "application/json;q=0, text/*", http.StatusNotAcceptable},
+		{"POST", "/v1/users", "text/plain", "", http.StatusUnsupportedMediaType},
+		{"POST", "/v1/auth", "application/xml", "", http.StatusUnsupportedMediaType},
+		{"PUT", "/v1/users/" + string(user.GetAll()[0].ID), "application/x-www-form-urlencoded", "", http.StatusUnsupportedMediaType},
+		{"GET", "/healthz", "", "text/html", http.StatusOK},
+	}
+	for _, test := range tests {
+		req, _ := http.NewRequest(test.method, server.URL+test.path, strings.NewReader("garbage"))
+		if test.contentType != "" {
+			req.Header.Set("Content-Type", test.contentType)
+		}
+		if test.accept != "" {
+			req.Header.Set("Accept", test.accept)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != test.status {
+			t.Errorf("%s %s: expected %d, got %d.", test.method, test.path, test.status, resp.StatusCode)
+		}
+	}
+}
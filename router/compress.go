@@ -0,0 +1,211 @@
+// Package router contains endpoint information for the service.
+//
+// sn - https://github.com/sn
+package router
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/sn/service/config"
+	"github.com/sn/service/logging"
+)
+
+// Compression is the response compression configuration. Compression is
+// disabled when no encodings are listed.
+var Compression = config.Default().Router.Compression
+
+// encoder is a compressor that can be reused for another response
+type encoder interface {
+	io.WriteCloser
+	Reset(w io.Writer)
+}
+
+// encoders pools the encoders of each content coding
+var encoders = map[string]*sync.Pool{
+	config.EncodingGzip: {New: func() interface{} {
+		return gzip.NewWriter(nil)
+	}},
+	config.EncodingZstd: {New: func() interface{} {
+		enc, err := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+		if err != nil {
+			panic(err)
+		}
+		return enc
+	}},
+}
+
+// acceptEncoding returns the first of the configured encodings with the
+// highest quality in an Accept-Encoding header, or "" if none is acceptable
+func acceptEncoding(header string) string {
+	qualities := map[string]float64{}
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+		coding := strings.ToLower(strings.TrimSpace(params[0]))
+		if coding == "x-gzip" {
+			coding = config.EncodingGzip
+		}
+		q := 1.0
+		for _, param := range params[1:] {
+			if value := strings.TrimSpace(param); strings.HasPrefix(value, "q=") {
+				var err error
+				if q, err = strconv.ParseFloat(value[2:], 64); err != nil {
+					q = 0
+				}
+			}
+		}
+		if coding != "" {
+			qualities[coding] = q
+		}
+	}
+
+	best, bestQ := "", 0.0
+	for _, encoding := range Compression.Encodings {
+		q, ok := qualities[encoding]
+		if !ok {
+			q = qualities["*"]
+		}
+		if q > bestQ {
+			best, bestQ = encoding, q
+		}
+	}
+	return best
+}
+
+// Compress compresses response bodies of at least Compression.MinSize bytes
+// with the encoding the client prefers by its Accept-Encoding header, and
+// decompresses gzip request bodies. Decompressed bodies are limited to
+// BodyLimit bytes like any other.
+func Compress(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding"))) {
+		case "", "identity":
+		case config.EncodingGzip, "x-gzip":
+			gz, err := gzip.NewReader(r.Body)
+			if err != nil {
+				w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, "Unable to read body.")
+				return
+			}
+			r.Body = struct {
+				io.Reader
+				io.Closer
+			}{gz, r.Body}
+			r.Header.Del("Content-Encoding")
+			r.Header.Del("Content-Length")
+			r.ContentLength = -1
+		default:
+			w.Header().Set("Accept-Encoding", config.EncodingGzip)
+			w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			fmt.Fprint(w, "Unsupported content encoding.")
+			return
+		}
+
+		if len(Compression.Encodings) == 0 {
+			h.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Add("Vary", "Accept-Encoding")
+		encoding := acceptEncoding(r.Header.Get("Accept-Encoding"))
+		if encoding == "" || r.Method == "HEAD" {
+			h.ServeHTTP(w, r)
+			return
+		}
+		cw := &compressWriter{ResponseWriter: w, encoding: encoding}
+		h.ServeHTTP(cw, r)
+		if err := cw.close(); err != nil {
+			logging.FromContext(r.Context()).Warn("compressing response failed", "error", err)
+		}
+	})
+}
+
+// compressWriter buffers a response until it has Compression.MinSize bytes,
+// then compresses the rest of it. Smaller responses are written as they are.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+	status   int
+	buf      []byte
+	started  bool
+	enc      encoder
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.status != 0 {
+		return
+	}
+	cw.status = status
+	if status == http.StatusNoContent || status == http.StatusNotModified {
+		cw.start(false)
+	}
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+	if cw.started {
+		if cw.enc != nil {
+			return cw.enc.Write(b)
+		}
+		return cw.ResponseWriter.Write(b)
+	}
+	cw.buf = append(cw.buf, b...)
+	if len(cw.buf) >= Compression.MinSize {
+		if err := cw.start(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// start writes the header and the buffered body, compressing them unless the
+// handler set an encoding of its own
+func (cw *compressWriter) start(compress bool) error {
+	cw.started = true
+	if compress && cw.Header().Get("Content-Encoding") == "" {
+		cw.Header().Set("Content-Encoding", cw.encoding)
+		cw.Header().Del("Content-Length")
+		cw.enc = encoders[cw.encoding].Get().(encoder)
+		cw.enc.Reset(cw.ResponseWriter)
+	}
+	cw.ResponseWriter.WriteHeader(cw.status)
+	buf := cw.buf
+	cw.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	var err error
+	if cw.enc != nil {
+		_, err = cw.enc.Write(buf)
+	} else {
+		_, err = cw.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+// close writes a response smaller than Compression.MinSize, or finishes a
+// compressed one
+func (cw *compressWriter) close() error {
+	if !cw.started {
+		if cw.status == 0 {
+			return nil
+		}
+		return cw.start(false)
+	}
+	if cw.enc == nil {
+		return nil
+	}
+	err := cw.enc.Close()
+	encoders[cw.encoding].Put(cw.enc)
+	cw.enc = nil
+	return err
+}
@@ -0,0 +1,192 @@
+// Package router contains endpoint information for the service.
+//
+// sn - https://github.com/sn
+package router
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestAcceptEncoding(t *testing.T) {
+	tests := map[string]string{
+		"":                          "",
+		"identity":                  "",
+		"gzip":                      "gzip",
+		"x-gzip":                    "gzip",
+		"GZIP, deflate":             "gzip",
+		"gzip, zstd":                "zstd",
+		"gzip;q=1, zstd;q=0.5":      "gzip",
+		"*":                         "zstd",
+		"zstd;q=0, *":               "gzip",
+		"zstd;q=0, gzip;q=0, *;q=1": "",
+		"br, deflate":               "",
+	}
+	for header, want := range tests {
+		if got := acceptEncoding(header); got != want {
+			t.Errorf("%q: expected %q, got %q.", header, want, got)
+		}
+	}
+}
+
+// decompress decompresses a body in a content coding
+func decompress(t *testing.T, encoding string, body []byte) []byte {
+	var r io.Reader
+	var err error
+	switch encoding {
+	case "gzip":
+		r, err = gzip.NewReader(bytes.NewReader(body))
+	case "zstd":
+		r, err = zstd.NewReader(bytes.NewReader(body))
+	default:
+		return body
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestCompress(t *testing.T) {
+	large := strings.Repeat("compressible ", 200)
+	tests := []struct {
+		acceptEncoding  string
+		status          int
+		contentEncoding string
+		body            string
+		encoding        string
+	}{
+		{"gzip", http.StatusOK, "", large, "gzip"},
+		{"gzip, zstd", http.StatusCreated, "", large, "zstd"},
+		{"gzip", http.StatusOK, "", "small", ""},
+		{"identity", http.StatusOK, "", large, ""},
+		{"", http.StatusOK, "", large, ""},
+		{"gzip", http.StatusNoContent, "", "", ""},
+		{"gzip", http.StatusOK, "br", large, "br"},
+	}
+	for _, test := range tests {
+		h := Compress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if test.contentEncoding != "" {
+				w.Header().Set("Content-Encoding", test.contentEncoding)
+			}
+			w.WriteHeader(test.status)
+			// written in pieces, so the threshold is crossed midway
+			for i := 0; i < len(test.body); i += 100 {
+				end := i + 100
+				if end > len(test.body) {
+					end = len(test.body)
+				}
+				w.Write([]byte(test.body[i:end]))
+			}
+		}))
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Accept-Encoding", test.acceptEncoding)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		if rec.Code != test.status {
+			t.Errorf("%q: expected %d, got %d.", test.acceptEncoding, test.status, rec.Code)
+		}
+		if got := rec.Header().Get("Content-Encoding"); got != test.encoding {
+			t.Errorf("%q: expected encoding %q, got %q.", test.acceptEncoding, test.encoding, got)
+		}
+		if rec.Header().Get("Vary") != "Accept-Encoding" {
+			t.Errorf("%q: expected Vary: Accept-Encoding, got %q.", test.acceptEncoding, rec.Header().Get("Vary"))
+		}
+		if body := decompress(t, test.encoding, rec.Body.Bytes()); string(body) != test.body {
+			t.Errorf("%q: expected the body to be decompressed, got %d bytes.", test.acceptEncoding, len(body))
+		}
+		if test.encoding != "" && test.encoding != test.contentEncoding && rec.Body.Len() >= len(test.body) {
+			t.Errorf("%q: expected the body to be compressed, got %d bytes.", test.acceptEncoding, rec.Body.Len())
+		}
+	}
+}
+
+func TestCompressDisabled(t *testing.T) {
+	prev := Compression
+	Compression.Encodings = nil
+	defer func() { Compression = prev }()
+
+	h := Compress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(bytes.Repeat([]byte("a"), 4096))
+	}))
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Header().Get("Content-Encoding") != "" || rec.Header().Get("Vary") != "" || rec.Body.Len() != 4096 {
+		t.Errorf("Expected the response to be uncompressed, got %q.", rec.Header().Get("Content-Encoding"))
+	}
+}
+
+func TestCompressedUserIndex(t *testing.T) {
+	// the default transport would decompress the response itself
+	c := &http.Client{Transport: &http.Transport{DisableCompression: true}}
+	req, _ := http.NewRequest("GET", server.URL+"/v1/users?limit=100", nil)
+	req.Header.Set("Accept-Encoding", "zstd")
+	resp, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	if resp.Header.Get("Content-Encoding") != "zstd" {
+		t.Fatalf("Expected a zstd response, got %q.", resp.Header.Get("Content-Encoding"))
+	}
+	if body = decompress(t, "zstd", body); !bytes.HasPrefix(body, []byte("[")) {
+		t.Errorf("Expected a list of users, got %q.", body)
+	}
+	if vary := resp.Header.Values("Vary"); !strings.Contains(strings.Join(vary, ","), "Accept-Encoding") {
+		t.Errorf("Expected Vary: Accept-Encoding, got %q.", vary)
+	}
+}
+
+func TestDecompressRequest(t *testing.T) {
+	gzipped := func(s string) []byte {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		gz.Write([]byte(s))
+		gz.Close()
+		return buf.Bytes()
+	}
+	tests := []struct {
+		contentEncoding string
+		body            []byte
+		status          int
+	}{
+		{"gzip", gzipped(`{"username":"harley","password":"1@E4s67890","email":"harley@example.com"}`), http.StatusCreated},
+		{"x-gzip", gzipped(`{"username":"indigo","password":"1@E4s67890","email":"indigo@example.com"}`), http.StatusCreated},
+		{"gzip", []byte(`{"username":"jules"}`), http.StatusBadRequest},
+		{"gzip", gzipped(`{"username":"kai","password":"1@E4s67890","email":"kai@example.com","padding":"` + strings.Repeat("a", int(BodyLimit)) + `"}`), http.StatusBadRequest},
+		{"br", []byte(`{}`), http.StatusUnsupportedMediaType},
+	}
+	for _, test := range tests {
+		req, _ := http.NewRequest("POST", server.URL+"/v1/users", bytes.NewReader(test.body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Content-Encoding", test.contentEncoding)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != test.status {
+			t.Errorf("%s %d bytes: expected %d, got %d %q.", test.contentEncoding, len(test.body), test.status, resp.StatusCode, body)
+		}
+		if test.status == http.StatusUnsupportedMediaType && resp.Header.Get("Accept-Encoding") != "gzip" {
+			t.Errorf("Expected the supported encodings, got %q.", resp.Header.Get("Accept-Encoding"))
+		}
+	}
+}
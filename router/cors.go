@@ -0,0 +1,136 @@
+// Package router contains endpoint information for the service.
+//
+// sn - https://github.com/sn
+package router
+
+import (
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/sn/service/config"
+)
+
+// CrossOrigin is the cross-origin resource sharing (CORS) configuration. CORS
+// is disabled when no origins are allowed.
+var CrossOrigin = config.Default().Router.CORS
+
+// methods are the methods routes can be registered for
+var methods = []string{"GET", "POST", "PUT", "PATCH", "DELETE"}
+
+// originAllowed reports whether an origin matches an allowed origin pattern,
+// where * matches any origin and other wildcards match within a host name,
+// such as https://*.example.com
+func originAllowed(origin string) bool {
+	if origin == "" {
+		return false
+	}
+	for _, pattern := range CrossOrigin.AllowedOrigins {
+		if pattern == "*" || pattern == origin {
+			return true
+		}
+		if ok, _ := path.Match(pattern, origin); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// contains reports whether a list contains a value, ignoring case
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == "*" || strings.EqualFold(item, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowOrigin sets the headers allowing the origin of a request
+func allowOrigin(w http.ResponseWriter, origin string) {
+	if contains(CrossOrigin.AllowedOrigins, "*") && !CrossOrigin.AllowCredentials {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+	} else {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Add("Vary", "Origin")
+	}
+	if CrossOrigin.AllowCredentials {
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+// CORS allows requests from the allowed origins, exposing the configured
+// response headers to them
+func CORS(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if origin := r.Header.Get("Origin"); originAllowed(origin) {
+			allowOrigin(w, origin)
+			if len(CrossOrigin.ExposedHeaders) > 0 {
+				w.Header().Set("Access-Control-Expose-Headers", strings.Join(CrossOrigin.ExposedHeaders, ", "))
+			}
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// allowedMethods returns the methods the router has routes for at the path of
+// a request
+func allowedMethods(router *mux.Router, r *http.Request) []string {
+	var allowed []string
+	for _, method := range methods {
+		req := r.Clone(r.Context())
+		req.Method = method
+		var match mux.RouteMatch
+		if router.Match(req, &match) && match.MatchErr == nil {
+			allowed = append(allowed, method)
+		}
+	}
+	return allowed
+}
+
+// options responds to OPTIONS requests for the paths of registered routes. A
+// CORS preflight request is answered with the methods and headers allowed for
+// its origin, or 403 if the origin, method, or headers are not allowed. Any
+// other OPTIONS request is answered with the Allow header.
+func options(router *mux.Router, w http.ResponseWriter, r *http.Request) {
+	allowed := append(allowedMethods(router, r), "OPTIONS")
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+
+	method := r.Header.Get("Access-Control-Request-Method")
+	if method == "" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.Header().Add("Vary", "Origin")
+	w.Header().Add("Vary", "Access-Control-Request-Method")
+	w.Header().Add("Vary", "Access-Control-Request-Headers")
+
+	origin := r.Header.Get("Origin")
+	if !originAllowed(origin) || !contains(allowed, method) || !contains(CrossOrigin.AllowedMethods, method) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	var headers []string
+	for _, header := range strings.Split(r.Header.Get("Access-Control-Request-Headers"), ",") {
+		if header = strings.TrimSpace(header); header == "" {
+			continue
+		}
+		if !contains(CrossOrigin.AllowedHeaders, header) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		headers = append(headers, header)
+	}
+
+	allowOrigin(w, origin)
+	w.Header().Set("Access-Control-Allow-Methods", strings.Join(CrossOrigin.AllowedMethods, ", "))
+	if len(headers) > 0 {
+		w.Header().Set("Access-Control-Allow-Headers", strings.Join(headers, ", "))
+	}
+	if CrossOrigin.MaxAge > 0 {
+		w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(CrossOrigin.MaxAge.Seconds())))
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
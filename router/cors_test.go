@@ -0,0 +1,135 @@
+// Package router contains endpoint information for the service.
+//
+// sn - https://github.com/sn
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sn/service/config"
+)
+
+// allowOrigins allows cross-origin requests from origins until the test ends
+func allowOrigins(t *testing.T, credentials bool, origins ...string) {
+	prev := CrossOrigin
+	CrossOrigin = config.Default().Router.CORS
+	CrossOrigin.AllowedOrigins = origins
+	CrossOrigin.AllowCredentials = credentials
+	t.Cleanup(func() { CrossOrigin = prev })
+}
+
+func TestCORS(t *testing.T) {
+	allowOrigins(t, false, "https://app.example.com", "https://*.example.org")
+	router := NewRouter()
+
+	tests := []struct {
+		origin  string
+		allowed bool
+	}{
+		{"https://app.example.com", true},
+		{"https://admin.example.org", true},
+		{"https://example.org", false},
+		{"https://evil.com", false},
+		{"", false},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest("GET", "/unknown", nil)
+		if test.origin != "" {
+			req.Header.Set("Origin", test.origin)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if got := rec.Header().Get("Access-Control-Allow-Origin"); (got != "" && got == test.origin) != test.allowed {
+			t.Errorf("%q: expected allowed to be %v, got origin %q.", test.origin, test.allowed, got)
+		}
+		if test.allowed && rec.Header().Get("Access-Control-Expose-Headers") != strings.Join(CrossOrigin.ExposedHeaders, ", ") {
+			t.Errorf("%q: expected headers to be exposed.", test.origin)
+		}
+	}
+
+	allowOrigins(t, false, "*")
+	req := httptest.NewRequest("GET", "/unknown", nil)
+	req.Header.Set("Origin", "https://any.example.net")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Header().Get("Access-Control-Allow-Origin") != "*" {
+		t.Error("Expected any origin to be allowed.")
+	}
+
+	allowOrigins(t, true, "*")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Header().Get("Access-Control-Allow-Origin") != "https://any.example.net" || rec.Header().Get("Access-Control-Allow-Credentials") != "true" {
+		t.Error("Expected the origin to be echoed with credentials.")
+	}
+}
+
+func TestPreflight(t *testing.T) {
+	allowOrigins(t, false, "https://app.example.com")
+	router := NewRouter()
+
+	tests := []struct {
+		path    string
+		origin  string
+		method  string
+		headers string
+		status  int
+		allow   string
+	}{
+		{"/users/1234", "https://app.example.com", "PATCH", "Authorization, If-Match", http.StatusNoContent, "GET, PUT, PATCH, DELETE, OPTIONS"},
+		{"/users", "https://app.example.com", "POST", "content-type", http.StatusNoContent, "GET, POST, OPTIONS"},
+		{"/auth", "https://app.example.com", "POST", "", http.StatusNoContent, "POST, DELETE, OPTIONS"},
+		{"/auth", "https://app.example.com", "PUT", "", http.StatusForbidden, "POST, DELETE, OPTIONS"},
+		{"/auth", "https://evil.com", "POST", "", http.StatusForbidden, "POST, DELETE, OPTIONS"},
+		{"/auth", "https://app.example.com", "POST", "X-Custom", http.StatusForbidden, "POST, DELETE, OPTIONS"},
+		{"/auth", "", "", "", http.StatusNoContent, "POST, DELETE, OPTIONS"},
+		{"/unknown", "https://app.example.com", "GET", "", http.StatusNotFound, ""},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest("OPTIONS", test.path, nil)
+		if test.origin != "" {
+			req.Header.Set("Origin", test.origin)
+		}
+		if test.method != "" {
+			req.Header.Set("Access-Control-Request-Method", test.method)
+		}
+		if test.headers != "" {
+			req.Header.Set("Access-Control-Request-Headers", test.headers)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != test.status {
+			t.Errorf("%s %s: expected status %d, got %d.", test.path, test.method, test.status, rec.Code)
+		}
+		if rec.Header().Get("Allow") != test.allow {
+			t.Errorf("%s %s: expected Allow %q, got %q.", test.path, test.method, test.allow, rec.Header().Get("Allow"))
+		}
+		if test.status != http.StatusNoContent || test.method == "" {
+			if rec.Header().Get("Access-Control-Allow-Methods") != "" {
+				t.Errorf("%s %s: expected no CORS headers.", test.path, test.method)
+			}
+			continue
+		}
+		if rec.Header().Get("Access-Control-Allow-Origin") != test.origin {
+			t.Errorf("%s %s: expected origin to be allowed.", test.path, test.method)
+		}
+		if rec.Header().Get("Access-Control-Allow-Methods") != "GET, POST, PUT, PATCH, DELETE" {
+			t.Errorf("%s %s: expected methods to be allowed.", test.path, test.method)
+		}
+		if rec.Header().Get("Access-Control-Allow-Headers") != test.headers {
+			t.Errorf("%s %s: expected headers %q to be allowed.", test.path, test.method, test.headers)
+		}
+		if rec.Header().Get("Access-Control-Max-Age") != "600" {
+			t.Errorf("%s %s: expected max age of 600 seconds.", test.path, test.method)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("PUT", "/auth", nil))
+	if rec.Code != http.StatusMethodNotAllowed || rec.Header().Get("Allow") != "POST, DELETE, OPTIONS" {
+		t.Errorf("Expected 405 with Allow header, got %d %q.", rec.Code, rec.Header().Get("Allow"))
+	}
+}
@@ -0,0 +1,62 @@
+// Package router contains endpoint information for the service.
+//
+// sn - https://github.com/sn
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/sn/service/helpers"
+	"github.com/sn/service/user"
+)
+
+// userETag returns the strong entity tag of a user, which changes whenever the
+// user's version does
+func userETag(u user.User) string {
+	return `"` + helpers.GenerateSha1Hash(fmt.Sprintf("%s:%d", u.ID, u.Version)) + `"`
+}
+
+// matchETag checks whether an If-Match or If-None-Match header value matches
+// an entity tag. Weak tags only match when weak comparison is allowed.
+func matchETag(header string, etag string, weak bool) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return true
+		}
+		if strings.HasPrefix(tag, "W/") {
+			if !weak {
+				continue
+			}
+			tag = tag[2:]
+		}
+		if tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// checkIfMatch responds with 412 when the request's If-Match header does not
+// match the user. It returns the version the change must be conditional on,
+// or 0 when the request has no If-Match header.
+func checkIfMatch(w http.ResponseWriter, r *http.Request, u user.User) (int, bool) {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		return 0, true
+	}
+	if !matchETag(header, userETag(u), false) {
+		preconditionFailed(w)
+		return 0, false
+	}
+	return u.Version, true
+}
+
+// preconditionFailed responds with 412
+func preconditionFailed(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusPreconditionFailed)
+	fmt.Fprint(w, "Precondition failed")
+}
@@ -0,0 +1,113 @@
+// Package router contains endpoint information for the service.
+//
+// sn - https://github.com/sn
+package router
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"strings"
+	"testing"
+
+	"github.com/sn/service/user"
+)
+
+func TestMatchETag(t *testing.T) {
+	tests := []struct {
+		header string
+		weak   bool
+		match  bool
+	}{
+		{`"a"`, false, true},
+		{`"b", "a"`, false, true},
+		{`"b"`, false, false},
+		{`*`, false, true},
+		{`W/"a"`, false, false},
+		{`W/"a"`, true, true},
+	}
+	for _, test := range tests {
+		if matchETag(test.header, `"a"`, test.weak) != test.match {
+			t.Errorf("%s: expected match to be %v.", test.header, test.match)
+		}
+	}
+}
+
+func TestUserETag(t *testing.T) {
+	addr, err := mail.ParseAddress("etag@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	u := user.Create(context.Background(), user.User{Username: "etag", Password: "1@E4s67890", Address: addr})
+	path := server.URL + "/users/" + string(u.ID)
+
+	do := func(method, body string, header http.Header) *http.Response {
+		req, err := http.NewRequest(method, path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	resp := do("GET", "", nil)
+	etag := resp.Header.Get("ETag")
+	if etag == "" {
+		t.Fatal("Expected ETag.")
+	}
+	if resp = do("GET", "", http.Header{"If-None-Match": {etag}}); resp.StatusCode != http.StatusNotModified {
+		t.Errorf("Expected 304, got %d.", resp.StatusCode)
+	}
+	if resp = do("GET", "", http.Header{"If-None-Match": {`"stale"`}}); resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected 200, got %d.", resp.StatusCode)
+	}
+
+	body := `{"username":"etag","password":"1@E4s67890","email":"etag@example.org"}`
+	if resp = do("PUT", body, http.Header{"If-Match": {`"stale"`}}); resp.StatusCode != http.StatusPreconditionFailed {
+		t.Errorf("Expected 412, got %d.", resp.StatusCode)
+	}
+	if resp = do("PUT", body, http.Header{"If-Match": {etag}}); resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected 200, got %d.", resp.StatusCode)
+	}
+	if resp.Header.Get("ETag") == etag || resp.Header.Get("ETag") == "" {
+		t.Error("Expected ETag to change.")
+	}
+
+	// The first ETag is now stale
+	if resp = do("PATCH", `{"username":"etagged"}`, http.Header{"If-Match": {etag}}); resp.StatusCode != http.StatusPreconditionFailed {
+		t.Errorf("Expected 412, got %d.", resp.StatusCode)
+	}
+	if resp = do("DELETE", "", http.Header{"If-Match": {etag}}); resp.StatusCode != http.StatusPreconditionFailed {
+		t.Errorf("Expected 412, got %d.", resp.StatusCode)
+	}
+	etag = do("GET", "", nil).Header.Get("ETag")
+	if resp = do("PATCH", `{"username":"etagged"}`, http.Header{"If-Match": {etag}}); resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected 200, got %d.", resp.StatusCode)
+	}
+	if resp = do("DELETE", "", http.Header{"If-Match": {resp.Header.Get("ETag")}}); resp.StatusCode != http.StatusNoContent {
+		t.Errorf("Expected 204, got %d.", resp.StatusCode)
+	}
+}
+
+func TestStoreError(t *testing.T) {
+	tests := map[error]int{
+		user.ErrNotFound:        http.StatusNotFound,
+		user.ErrVersionMismatch: http.StatusPreconditionFailed,
+	}
+	for err, status := range tests {
+		if serr, ok := storeError(err).(*statusError); !ok || serr.status != status {
+			t.Errorf("%v: expected status %d, got %v.", err, status, storeError(err))
+		}
+	}
+	if err := fmt.Errorf("Other."); storeError(err) != err {
+		t.Error("Expected other errors to be returned unchanged.")
+	}
+}
@@ -4,192 +4,228 @@
 package router
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
-	"log"
 	"net/http"
 	"net/mail"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/sn/service/helpers"
+	"github.com/sn/service/metrics"
 	"github.com/sn/service/session"
 	"github.com/sn/service/types"
 	"github.com/sn/service/user"
 )
 
 // Index handles GET /index
-var Index = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+var Index = handler(func(w http.ResponseWriter, r *http.Request) error {
 	if auth := r.Header["Authorization"]; auth != nil {
-		if s := session.Find(auth[0]); s.ID != "" {
-			if time.Now().Before(s.Expires) {
-				u := user.FindByID(s.UserID)
+		if s := session.Find(r.Context(), auth[0]); s.ID != "" {
+			u := user.FindByID(r.Context(), s.UserID)
+			if time.Now().Before(s.Expires) && !u.Deactivated {
 				fmt.Fprintf(w, "Welcome, %s!\n", u.Username)
-				err := session.Bump(s.ID)
-				if err != nil {
-					panic(err)
-				}
-				return
+				return session.Bump(r.Context(), s.ID)
 			}
-			session.Expire(s.ID)
+			session.Expire(r.Context(), s.ID)
 		}
 	}
 	fmt.Fprint(w, "Welcome!\n")
+	return nil
 })
 
 // Auth handles POST /auth
-var Auth = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-	u := user.User{}
-	body, err := ioutil.ReadAll(io.LimitReader(r.Body, 1048576))
+var Auth = handler(func(w http.ResponseWriter, r *http.Request) error {
+	var u authInput
+	c, err := requestCodec(r)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
-	if err := r.Body.Close(); err != nil {
-		log.Fatal(err)
+	body, err := readBody(r)
+	if err != nil {
+		return err
 	}
 
 	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
-	if err := json.Unmarshal(body, &u); err != nil {
+	if err := c.Unmarshal(body, &u); err != nil {
 		w.WriteHeader(http.StatusBadRequest)
-		if err := json.NewEncoder(w).Encode(err); err != nil {
-			panic(err)
-		}
+		return json.NewEncoder(w).Encode(err)
 	}
-	refUser := user.FindByID(u.ID)
+	refUser := user.FindByID(r.Context(), u.ID)
 	if len(refUser.ID) > 0 {
-		if user.CheckPassword(refUser, u.Password) {
+		if user.CheckPassword(r.Context(), refUser, u.Password) {
+			if refUser.Deactivated {
+				metrics.AuthAttempts.WithLabelValues("failure").Inc()
+				w.WriteHeader(http.StatusForbidden)
+				return nil
+			}
+			metrics.AuthAttempts.WithLabelValues("success").Inc()
 			w.WriteHeader(http.StatusOK)
-			s := session.Create(refUser.ID)
+			s := session.Create(r.Context(), refUser.ID)
 			fmt.Fprintf(w, "%s", helpers.GenerateSha1Hash(string(s.ID)))
-			return
+			return nil
 		}
+		metrics.AuthAttempts.WithLabelValues("failure").Inc()
 		w.WriteHeader(http.StatusUnauthorized)
-		return
+		return nil
 	}
+	metrics.AuthAttempts.WithLabelValues("failure").Inc()
 	w.WriteHeader(http.StatusNotFound)
+	return nil
 })
 
 // UserIndex handles GET /users
-var UserIndex = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
-	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(user.GetAll()); err != nil {
-		log.Fatal(err)
+//
+// Users are paginated with opaque cursors, and can be filtered and sorted with
+// the query parameters:
+// - limit, the page size, up to user.MaxLimit,
+// - cursor, a cursor from the Link header of another page,
+// - username_prefix, for usernames starting with the prefix,
+// - created_after and created_before, for users created between RFC 3339 times,
+// - role, for users with the role,
+// - sort, created or username, prefixed by "-" for descending order.
+var UserIndex = handler(func(w http.ResponseWriter, r *http.Request) error {
+	params := r.URL.Query()
+	q := user.Query{
+		Cursor:         params.Get("cursor"),
+		UsernamePrefix: params.Get("username_prefix"),
+		Role:           params.Get("role"),
+		Sort:           params.Get("sort"),
+	}
+	var err error
+	if limit := params.Get("limit"); limit != "" {
+		if q.Limit, err = strconv.Atoi(limit); err != nil || q.Limit <= 0 {
+			w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, "Invalid limit.")
+			return nil
+		}
 	}
+	for name, t := range map[string]*time.Time{"created_after": &q.CreatedAfter, "created_before": &q.CreatedBefore} {
+		if value := params.Get(name); value != "" {
+			if *t, err = time.Parse(time.RFC3339, value); err != nil {
+				w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprintf(w, "Invalid %s.", name)
+				return nil
+			}
+		}
+	}
+
+	page, err := user.List(r.Context(), q)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, err)
+		return nil
+	}
+
+	var links []string
+	for rel, c := range map[string]string{"next": page.Next, "prev": page.Prev} {
+		if c != "" {
+			params.Set("cursor", c)
+			u := url.URL{Path: r.URL.Path, RawQuery: params.Encode()}
+			links = append(links, fmt.Sprintf(`<%s>; rel="%s"`, u.String(), rel))
+		}
+	}
+	if len(links) > 0 {
+		sort.Strings(links)
+		w.Header().Set("Link", strings.Join(links, ", "))
+	}
+	return respond(w, r, http.StatusOK, page.Users)
 })
 
-// UserShow handles GET /users/:userID
-var UserShow = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	userID := types.UUID(vars["userID"])
-	user := user.FindByID(userID)
+// UserShow handles GET /users/:userId
+var UserShow = handler(func(w http.ResponseWriter, r *http.Request) error {
+	userID := routeUserID(r)
+	user := user.FindByID(r.Context(), userID)
 	if len(user.ID) > 0 {
-		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
-		w.WriteHeader(http.StatusOK)
-		if err := json.NewEncoder(w).Encode(user); err != nil {
-			log.Fatal(err)
+		etag := userETag(user)
+		w.Header().Set("ETag", etag)
+		if header := r.Header.Get("If-None-Match"); header != "" && matchETag(header, etag, true) {
+			w.WriteHeader(http.StatusNotModified)
+			return nil
 		}
-		return
+		return respond(w, r, http.StatusOK, user)
 	}
 
 	// If we didn't find it, 404
 	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
 	w.WriteHeader(http.StatusNotFound)
 	fmt.Fprint(w, "Not Found")
+	return nil
 })
 
-// UserCreate handles POST /users/:userID
-var UserCreate = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-	var input struct {
-		Username string `json:"username"`
-		Password string `json:"password"`
-		Address  string `json:"email"`
-	}
-	body, err := ioutil.ReadAll(io.LimitReader(r.Body, 1048576))
+// UserCreate handles POST /users
+var UserCreate = handler(func(w http.ResponseWriter, r *http.Request) error {
+	var input userInput
+	c, err := requestCodec(r)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
-	if err := r.Body.Close(); err != nil {
-		log.Fatal(err)
+	body, err := readBody(r)
+	if err != nil {
+		return err
 	}
-	if err := json.Unmarshal(body, &input); err != nil {
+	if err := c.Unmarshal(body, &input); err != nil {
 		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
 		w.WriteHeader(http.StatusBadRequest)
 		fmt.Fprint(w, "Bad Request")
-		return
+		return nil
 	}
 
+	u, err := createUser(r.Context(), input)
+	if err != nil {
+		return err
+	}
+	return respond(w, r, http.StatusCreated, u)
+})
+
+// createUser validates and creates a user
+func createUser(ctx context.Context, input userInput) (user.User, error) {
 	u := user.User{}
 	u.Username = input.Username
 	u.Password = input.Password
-	u.Address, err = mail.ParseAddress(input.Address)
+	address, err := mail.ParseAddress(input.Address)
 	if err != nil {
-		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
-		w.WriteHeader(http.StatusBadRequest)
-		fmt.Fprint(w, "Unable to parse address.")
-		return
+		return u, &statusError{http.StatusBadRequest, "Unable to parse address."}
 	}
+	u.Address = address
 	if err := user.Validate(u); err != nil {
-		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
-		w.WriteHeader(http.StatusBadRequest)
-		fmt.Fprint(w, err)
-		return
+		return u, &statusError{validationStatus(err), err.Error()}
 	}
-	if findUser := user.FindByUsername(u.Username); len(findUser.ID) > 0 && findUser.ID != u.ID {
-		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
-		w.WriteHeader(http.StatusConflict)
-		fmt.Fprint(w, "Username is taken.")
-		return
-	}
-	if findUser := user.FindByAddress(u.Address); len(findUser.ID) > 0 && findUser.ID != u.ID {
-		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
-		w.WriteHeader(http.StatusConflict)
-		fmt.Fprint(w, "Address is taken.")
-		return
-	}
-
+	u, err = user.CreateUnique(ctx, u)
 	if err != nil {
-		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
-		w.WriteHeader(http.StatusBadRequest)
-		fmt.Fprint(w, "Email is invalid.")
-		return
+		return u, &statusError{http.StatusConflict, err.Error()}
 	}
+	return u, nil
+}
 
-	u = user.Create(u)
-	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
-	w.WriteHeader(http.StatusCreated)
-	if err := json.NewEncoder(w).Encode(u); err != nil {
-		fmt.Fprint(w, err)
-		log.Fatal(err)
-	}
-})
-
-// UserUpdate handles PUT /users/:userID
-var UserUpdate = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-	var input struct {
-		Username string `json:"username"`
-		Password string `json:"password"`
-		Address  string `json:"email"`
-	}
+// UserUpdate handles PUT /users/:userId
+var UserUpdate = handler(func(w http.ResponseWriter, r *http.Request) error {
+	var input userInput
 
-	vars := mux.Vars(r)
-	userID := types.UUID(vars["userID"])
+	userID := routeUserID(r)
 
-	body, err := ioutil.ReadAll(io.LimitReader(r.Body, 1048576))
+	c, err := requestCodec(r)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
-	if err := r.Body.Close(); err != nil {
-		log.Fatal(err)
+	body, err := readBody(r)
+	if err != nil {
+		return err
 	}
-	if err := json.Unmarshal(body, &input); err != nil {
+	if err := c.Unmarshal(body, &input); err != nil {
 		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
 		w.WriteHeader(http.StatusBadRequest)
-		if err := json.NewEncoder(w).Encode(err); err != nil {
-			log.Fatal(err)
-		}
+		return json.NewEncoder(w).Encode(err)
 	}
 
 	u := user.User{}
@@ -201,125 +237,255 @@ var UserUpdate = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
 		w.WriteHeader(http.StatusBadRequest)
 		fmt.Fprint(w, "Unable to parse address.")
-		return
+		return nil
 	}
 
 	if err := user.Validate(u); err != nil {
 		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
-		w.WriteHeader(http.StatusBadRequest)
+		w.WriteHeader(validationStatus(err))
 		fmt.Fprint(w, err)
-		return
+		return nil
 	}
-	if findUser := user.FindByID(u.ID); len(findUser.ID) == 0 {
+	existing := user.FindByID(r.Context(), u.ID)
+	if len(existing.ID) == 0 {
 		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
 		w.WriteHeader(http.StatusNotFound)
 		fmt.Fprint(w, "Not found")
-		return
+		return nil
 	}
-	if findUser := user.FindByUsername(u.Username); len(findUser.ID) > 0 && findUser.ID != u.ID {
+	version, ok := checkIfMatch(w, r, existing)
+	if !ok {
+		return nil
+	}
+	u.Version = version
+	if findUser := user.FindByUsernameSkeleton(r.Context(), u.Username); len(findUser.ID) > 0 && findUser.ID != u.ID {
 		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
 		w.WriteHeader(http.StatusConflict)
 		fmt.Fprint(w, "Username is taken.")
-		return
+		return nil
 	}
-	if findUser := user.FindByAddress(u.Address); len(findUser.ID) > 0 && findUser.ID != u.ID {
+	if findUser := user.FindByAddress(r.Context(), u.Address); len(findUser.ID) > 0 && findUser.ID != u.ID {
 		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
 		w.WriteHeader(http.StatusConflict)
 		fmt.Fprint(w, "Address is taken.")
-		return
+		return nil
 	}
 
-	u = user.Update(u)
-	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
-	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(u); err != nil {
-		log.Fatal(err)
+	if u, err = user.Update(r.Context(), u); err != nil {
+		return storeError(err)
 	}
+	w.Header().Set("ETag", userETag(u))
+	return respond(w, r, http.StatusOK, u)
 })
 
-// UserPatch handles PATCH /users/:userID
-var UserPatch = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-	var input struct {
-		Username string `json:"username"`
-		Password string `json:"password"`
-		Address  string `json:"email"`
-	}
-
-	vars := mux.Vars(r)
-	userID := types.UUID(vars["userID"])
+// UserPatch handles PATCH /users/:userId
+//
+// The body is a JSON Merge Patch (RFC 7396) or, when sent as
+// application/json-patch+json, a JSON Patch (RFC 6902). Only the fields set by
+// the patch are validated. Only admins can change roles. Removing the role
+// resets it to user; the other fields cannot be removed.
+var UserPatch = handler(func(w http.ResponseWriter, r *http.Request) error {
+	userID := routeUserID(r)
 
-	body, err := ioutil.ReadAll(io.LimitReader(r.Body, 1048576))
+	body, err := readBody(r)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
-	if err := r.Body.Close(); err != nil {
-		log.Fatal(err)
-	}
-	if err := json.Unmarshal(body, &input); err != nil {
+
+	existing := user.FindByID(r.Context(), userID)
+	if len(existing.ID) == 0 {
 		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
-		w.WriteHeader(http.StatusBadRequest)
-		fmt.Fprint(w, "Bad Request")
-		return
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, "Not found")
+		return nil
+	}
+	version, ok := checkIfMatch(w, r, existing)
+	if !ok {
+		return nil
+	}
+	fields, err := applyPatch(r.Header.Get("Content-Type"), body, existing)
+	if err != nil {
+		if serr, ok := err.(*statusError); ok && serr.status == http.StatusUnsupportedMediaType {
+			w.Header().Set("Accept-Patch", MergePatchType+", "+JSONPatchType)
+		}
+		return err
 	}
 
 	u := user.User{}
 	u.ID = userID
-	u.Username = input.Username
-	u.Password = input.Password
-	u.Address, err = mail.ParseAddress(input.Address)
-	if err != nil {
-		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
-		w.WriteHeader(http.StatusBadRequest)
-		fmt.Fprint(w, "Unable to parse address.")
-		return
+	u.Version = version
+	if role, ok := fields["role"]; ok {
+		if role != user.RoleUser && role != user.RoleAdmin {
+			w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, "Role must be user or admin.")
+			return nil
+		}
+		_, admin, err := authenticate(r)
+		if err != nil {
+			return err
+		}
+		if admin.Role != user.RoleAdmin {
+			w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, "Only admins can change roles.")
+			return nil
+		}
+		u.Role = role
+	}
+	if username, ok := fields["username"]; ok {
+		if username == "" {
+			w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, "Username is invalid.")
+			return nil
+		}
+		u.Username = username
+	}
+	if password, ok := fields["password"]; ok {
+		if password == "" {
+			w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, "Password must be 10 characters or longer.")
+			return nil
+		}
+		u.Password = password
+	}
+	if address, ok := fields["email"]; ok {
+		u.Address, err = mail.ParseAddress(address)
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, "Unable to parse address.")
+			return nil
+		}
 	}
 	if err := user.Validate(u); err != nil {
 		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
-		w.WriteHeader(http.StatusBadRequest)
+		w.WriteHeader(validationStatus(err))
 		fmt.Fprint(w, err)
-		return
-	}
-	if findUser := user.FindByID(u.ID); len(findUser.ID) == 0 {
-		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
-		w.WriteHeader(http.StatusNotFound)
-		fmt.Fprint(w, "Not found")
-		return
-	}
-	if findUser := user.FindByUsername(u.Username); len(findUser.ID) > 0 && findUser.ID != u.ID {
-		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
-		w.WriteHeader(http.StatusConflict)
-		fmt.Fprint(w, "Username is taken.")
-		return
+		return nil
+	}
+	if u.Username != "" {
+		if findUser := user.FindByUsernameSkeleton(r.Context(), u.Username); len(findUser.ID) > 0 && findUser.ID != u.ID {
+			w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+			w.WriteHeader(http.StatusConflict)
+			fmt.Fprint(w, "Username is taken.")
+			return nil
+		}
 	}
-	if findUser := user.FindByAddress(u.Address); len(findUser.ID) > 0 && findUser.ID != u.ID {
-		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
-		w.WriteHeader(http.StatusConflict)
-		fmt.Fprint(w, "Address is taken.")
-		return
+	if u.Address != nil {
+		if findUser := user.FindByAddress(r.Context(), u.Address); len(findUser.ID) > 0 && findUser.ID != u.ID {
+			w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+			w.WriteHeader(http.StatusConflict)
+			fmt.Fprint(w, "Address is taken.")
+			return nil
+		}
 	}
 
-	u = user.Patch(u)
-	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
-	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(u); err != nil {
-		log.Fatal(err)
+	if u, err = user.Patch(r.Context(), u); err != nil {
+		return storeError(err)
 	}
+	w.Header().Set("ETag", userETag(u))
+	return respond(w, r, http.StatusOK, u)
 })
 
-// UserDelete handles DELETE /users/:userID
-var UserDelete = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	userID := types.UUID(vars["userID"])
+// UserDelete handles DELETE /users/:userId
+var UserDelete = handler(func(w http.ResponseWriter, r *http.Request) error {
+	userID := routeUserID(r)
 
-	if err := user.Delete(userID); err == nil {
-		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
-		w.WriteHeader(http.StatusNoContent)
-		fmt.Fprint(w, err)
-		return
+	existing := user.FindByID(r.Context(), userID)
+	if len(existing.ID) > 0 {
+		version, ok := checkIfMatch(w, r, existing)
+		if !ok {
+			return nil
+		}
+		err := user.DeleteVersion(r.Context(), userID, version)
+		if err == nil {
+			w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+			w.WriteHeader(http.StatusNoContent)
+			return nil
+		}
+		if err == user.ErrVersionMismatch {
+			preconditionFailed(w)
+			return nil
+		}
 	}
 
 	// If we didn't find it, 404
 	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
 	w.WriteHeader(http.StatusNotFound)
 	fmt.Fprint(w, "Not found")
+	return nil
 })
+
+// storeError returns the *statusError of an error of the user store
+func storeError(err error) error {
+	switch err {
+	case user.ErrNotFound:
+		return &statusError{http.StatusNotFound, "Not found"}
+	case user.ErrVersionMismatch:
+		return &statusError{http.StatusPreconditionFailed, "Precondition failed"}
+	}
+	return err
+}
+
+// readBody reads the request body, up to BodyLimit bytes
+func readBody(r *http.Request) ([]byte, error) {
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, BodyLimit))
+	if err != nil {
+		return nil, &statusError{http.StatusBadRequest, "Unable to read body."}
+	}
+	return body, r.Body.Close()
+}
+
+// userInput is the body of a request creating or replacing a user
+type userInput struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Address  string `json:"email"`
+}
+
+// authInput is the body of a request starting a session
+type authInput struct {
+	ID       types.UUID
+	Password string
+}
+
+// statusError is an error with the status code it should be reported with
+type statusError struct {
+	status  int
+	message string
+}
+
+func (e *statusError) Error() string {
+	return e.message
+}
+
+// validationStatus returns the status code for a user.Validate error. Reserved
+// usernames are reported as taken ones are, with 409.
+func validationStatus(err error) int {
+	if err == user.ErrUsernameReserved {
+		return http.StatusConflict
+	}
+	return http.StatusBadRequest
+}
+
+// routeUserID returns the canonical user ID from the route variables
+func routeUserID(r *http.Request) types.UUID {
+	id, _ := types.Parse(mux.Vars(r)["userId"])
+	return id
+}
+
+// requireUUID responds with 400 when the named route variable is not a UUID
+func requireUUID(name string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := types.UUID(mux.Vars(r)[name]).Validate(); err != nil {
+			w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, "Invalid ID.")
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
@@ -5,6 +5,7 @@ package router
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -17,8 +18,11 @@ import (
 	"testing"
 	"time"
 
+	"github.com/sn/service/helpers"
 	"github.com/sn/service/session"
 	"github.com/sn/service/user"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
 )
 
 var (
@@ -71,7 +75,173 @@ func TestIndex(t *testing.T) {
 	}
 }
 
+func TestRoutes(t *testing.T) {
+	users := user.GetAll()
+	alex, blake, corey, devon := users[0], users[1], users[2], users[3]
+	unknownID := helpers.GenerateUUID()
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{"index", "GET", "/", "", http.StatusOK},
+		{"index method", "POST", "/", "", http.StatusMethodNotAllowed},
+		{"unknown path", "GET", "/unknown", "", http.StatusNotFound},
+
+		{"auth", "POST", "/v1/auth", `{"ID":"` + string(alex.ID) + `","Password":"1@E4s67890"}`, http.StatusOK},
+		{"auth wrong password", "POST", "/v1/auth", `{"ID":"` + string(alex.ID) + `","Password":"wrong"}`, http.StatusUnauthorized},
+		{"auth unknown user", "POST", "/v1/auth", `{"ID":"` + string(unknownID) + `","Password":"1@E4s67890"}`, http.StatusNotFound},
+		{"auth bad request", "POST", "/v1/auth", `{`, http.StatusBadRequest},
+		{"auth method", "GET", "/v1/auth", "", http.StatusMethodNotAllowed},
+
+		{"metrics", "GET", "/metrics", "", http.StatusOK},
+		{"healthz", "GET", "/healthz", "", http.StatusOK},
+		{"readyz", "GET", "/readyz", "", http.StatusOK},
+
+		{"user index", "GET", "/v1/users", "", http.StatusOK},
+		{"user index method", "DELETE", "/v1/users", "", http.StatusMethodNotAllowed},
+
+		{"user create", "POST", "/v1/users", `{"username":"emery","password":"1@E4s67890","email":"emery@example.com"}`, http.StatusCreated},
+		{"user create bad request", "POST", "/v1/users", `{`, http.StatusBadRequest},
+		{"user create bad address", "POST", "/v1/users", `{"username":"finley","password":"1@E4s67890","email":"finley"}`, http.StatusBadRequest},
+		{"user create invalid", "POST", "/v1/users", `{"username":"@@","password":"1@E4s67890","email":"finley@example.com"}`, http.StatusBadRequest},
+		{"user create reserved", "POST", "/v1/users", `{"username":"admin","password":"1@E4s67890","email":"finley@example.com"}`, http.StatusConflict},
+		{"user create username taken", "POST", "/v1/users", `{"username":"Alex","password":"1@E4s67890","email":"finley@example.com"}`, http.StatusConflict},
+		{"user create address taken", "POST", "/v1/users", `{"username":"finley","password":"1@E4s67890","email":"ALEX@example.com"}`, http.StatusConflict},
+
+		{"user show", "GET", "/v1/users/" + string(alex.ID), "", http.StatusOK},
+		{"user show uppercase", "GET", "/v1/users/" + strings.ToUpper(string(alex.ID)), "", http.StatusOK},
+		{"user show not found", "GET", "/v1/users/" + string(unknownID), "", http.StatusNotFound},
+		{"user show malformed", "GET", "/v1/users/garbage", "", http.StatusBadRequest},
+
+		{"user update", "PUT", "/v1/users/" + string(blake.ID), `{"username":"blake","password":"1@E4s67890","email":"blake@example.org"}`, http.StatusOK},
+		{"user update bad request", "PUT", "/v1/users/" + string(blake.ID), `{`, http.StatusBadRequest},
+		{"user update bad address", "PUT", "/v1/users/" + string(blake.ID), `{"username":"blake","password":"1@E4s67890","email":"blake"}`, http.StatusBadRequest},
+		{"user update invalid", "PUT", "/v1/users/" + string(blake.ID), `{"username":"@@","password":"1@E4s67890","email":"blake@example.org"}`, http.StatusBadRequest},
+		{"user update username taken", "PUT", "/v1/users/" + string(blake.ID), `{"username":"corey","password":"1@E4s67890","email":"blake@example.org"}`, http.StatusConflict},
+		{"user update address taken", "PUT", "/v1/users/" + string(blake.ID), `{"username":"blake","password":"1@E4s67890","email":"corey@example.com"}`, http.StatusConflict},
+		{"user update not found", "PUT", "/v1/users/" + string(unknownID), `{"username":"finley","password":"1@E4s67890","email":"finley@example.com"}`, http.StatusNotFound},
+		{"user update malformed", "PUT", "/v1/users/garbage", `{}`, http.StatusBadRequest},
+
+		{"user patch", "PATCH", "/v1/users/" + string(corey.ID), `{"username":"Corey","email":"corey@example.org"}`, http.StatusOK},
+		{"user patch bad request", "PATCH", "/v1/users/" + string(corey.ID), `{`, http.StatusBadRequest},
+		{"user patch invalid", "PATCH", "/v1/users/" + string(corey.ID), `{"username":"@@","email":"corey@example.org"}`, http.StatusBadRequest},
+		{"user patch username taken", "PATCH", "/v1/users/" + string(corey.ID), `{"username":"alex","email":"corey@example.org"}`, http.StatusConflict},
+		{"user patch address taken", "PATCH", "/v1/users/" + string(corey.ID), `{"email":"alex@example.com"}`, http.StatusConflict},
+		{"user patch not found", "PATCH", "/v1/users/" + string(unknownID), `{"email":"finley@example.com"}`, http.StatusNotFound},
+		{"user patch malformed", "PATCH", "/v1/users/garbage", `{}`, http.StatusBadRequest},
+
+		{"user delete", "DELETE", "/v1/users/" + string(devon.ID), "", http.StatusNoContent},
+		{"user delete not found", "DELETE", "/v1/users/" + string(devon.ID), "", http.StatusNotFound},
+		{"user delete malformed", "DELETE", "/v1/users/garbage", "", http.StatusBadRequest},
+		{"user method", "POST", "/v1/users/" + string(alex.ID), "", http.StatusMethodNotAllowed},
+	}
+
+	for _, test := range tests {
+		req, err := http.NewRequest(test.method, server.URL+test.path, strings.NewReader(test.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != test.status {
+			t.Errorf("%s: %s %s: expected status %d, got %d.", test.name, test.method, test.path, test.status, resp.StatusCode)
+		}
+	}
+
+	if u := user.FindByID(context.Background(), blake.ID); u.Address.Address != "blake@example.org" {
+		t.Error("User was not updated.")
+	}
+	if u := user.FindByID(context.Background(), corey.ID); u.Username != "Corey" {
+		t.Error("User was not patched.")
+	}
+	if u := user.FindByID(context.Background(), devon.ID); len(u.ID) > 0 {
+		t.Error("User was not deleted.")
+	}
+}
+
+func TestUserIndex(t *testing.T) {
+	path := server.URL + "/users?limit=1&sort=username"
+	var seen int
+	for path != "" {
+		resp, err := http.Get(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var users []user.User
+		if err := json.NewDecoder(resp.Body).Decode(&users); err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if len(users) != 1 {
+			t.Fatalf("Expected one user per page, got %d.", len(users))
+		}
+		seen++
+
+		path = ""
+		for _, link := range strings.Split(resp.Header.Get("Link"), ", ") {
+			if strings.HasSuffix(link, `rel="next"`) {
+				path = server.URL + strings.Trim(strings.Split(link, ";")[0], "<>")
+			}
+		}
+	}
+	if seen != len(user.GetAll()) {
+		t.Errorf("Expected %d users, got %d.", len(user.GetAll()), seen)
+	}
+
+	for _, query := range []string{"limit=0", "limit=x", "sort=password", "cursor=garbage", "created_after=yesterday"} {
+		resp, err := http.Get(server.URL + "/users?" + query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d.", query, resp.StatusCode)
+		}
+	}
+}
+
+func TestUpdateKeepsDeactivated(t *testing.T) {
+	addr, _ := mail.ParseAddress("dormant@example.com")
+	u := user.Create(context.Background(), user.User{Username: "dormant", Password: "1@E4s67890", Address: addr})
+	user.Deactivate(context.Background(), u.ID)
+	body := `{"username":"dormant","password":"1@E4s67890","email":"dormant@example.com"}`
+	req, _ := http.NewRequest("PUT", server.URL+"/v1/users/"+string(u.ID), strings.NewReader(body))
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !user.FindByID(context.Background(), u.ID).Deactivated {
+		t.Errorf("Expected the user to stay deactivated, got %d.", resp.StatusCode)
+	}
+}
+
+func TestUserOmitsPassword(t *testing.T) {
+	u := user.GetAll()[0]
+	for _, accept := range []string{"application/json", "application/msgpack", "application/cbor"} {
+		req, _ := http.NewRequest("GET", server.URL+"/v1/users/"+string(u.ID), nil)
+		req.Header.Set("Accept", accept)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || bytes.Contains(body, []byte("Password")) || bytes.Contains(body, []byte(u.Password)) {
+			t.Errorf("%s: expected the user without its password hash, got %d %q.", accept, resp.StatusCode, body)
+		}
+	}
+}
+
 func TestMain(m *testing.M) {
+	RateLimits.Requests = 0
 	router := NewRouter()
 
 	server = httptest.NewServer(router)
@@ -79,13 +249,13 @@ func TestMain(m *testing.M) {
 
 	usernames := [4]string{"alex", "blake", "corey", "devon"}
 	for _, un := range usernames {
-		addr, err := mail.ParseAddress(strings.Title(un) + "<" + un + "@example.com>")
+		addr, err := mail.ParseAddress(cases.Title(language.Und).String(un) + "<" + un + "@example.com>")
 		if err != nil {
 			log.Fatal(err)
 		}
 		u := user.User{Username: un, Password: "1@E4s67890", Address: addr, Created: time.Now()}
-		u = user.Create(u)
-		session.Create(u.ID)
+		u = user.Create(context.Background(), u)
+		session.Create(context.Background(), u.ID)
 	}
 
 	os.Exit(m.Run())
@@ -93,7 +263,7 @@ func TestMain(m *testing.M) {
 
 func getAuthToken(user user.User) (string, error) {
 	payload := new(bytes.Buffer)
-	json.NewEncoder(payload).Encode(user)
+	json.NewEncoder(payload).Encode(authInput{user.ID, user.Password})
 	resp, err := http.Post(server.URL+"/auth", "application/json; charset=utf-8", payload)
 	if err != nil {
 		return "", err
@@ -111,3 +281,14 @@ func getAuthToken(user user.User) (string, error) {
 	}
 	return string(authToken), nil
 }
+
+// newAdmin creates an admin and returns a session token of the admin
+func newAdmin(t *testing.T, username string) string {
+	addr, _ := mail.ParseAddress(username + "@example.com")
+	admin := user.Create(context.Background(), user.User{Username: username, Password: "1@E4s67890", Address: addr, Role: user.RoleAdmin, Created: time.Now()})
+	token, err := getAuthToken(user.User{ID: admin.ID, Password: "1@E4s67890"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
@@ -0,0 +1,205 @@
+// Package router contains endpoint information for the service.
+//
+// sn - https://github.com/sn
+package router
+
+import (
+	"bytes"
+	"container/list"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sn/service/helpers"
+)
+
+// IdempotencyWindow is how long a response is replayed for requests with the
+// same Idempotency-Key
+var IdempotencyWindow = 24 * time.Hour
+
+// IdempotencyKeys is the most idempotency keys remembered. When a new key
+// would exceed it, the oldest key is forgotten.
+var IdempotencyKeys = 100000
+
+// idempotentResponse is a response recorded for an idempotency key
+type idempotentResponse struct {
+	key         string
+	fingerprint string
+	done        bool
+	status      int
+	header      http.Header
+	body        []byte
+	expires     time.Time
+	element     *list.Element
+}
+
+// idempotencyStore holds the responses by key, and the keys in the order
+// they were first used. Every response is kept for the same window, so they
+// expire in about that order, and the expired ones are found at the front,
+// between responses still in progress.
+type idempotencyStore struct {
+	sync.Mutex
+	responses map[string]*idempotentResponse
+	order     *list.List
+}
+
+var idempotency = &idempotencyStore{responses: map[string]*idempotentResponse{}, order: list.New()}
+
+// start returns a copy of the response recorded for a key, or records a new
+// response in progress for it, reporting whether one was recorded
+func (s *idempotencyStore) start(key, fingerprint string, now time.Time) (*idempotentResponse, idempotentResponse, bool) {
+	s.Lock()
+	defer s.Unlock()
+	for e := s.order.Front(); e != nil; {
+		resp, next := e.Value.(*idempotentResponse), e.Next()
+		if resp.done {
+			if !now.After(resp.expires) {
+				break
+			}
+			s.remove(resp)
+		}
+		e = next
+	}
+	if resp, ok := s.responses[key]; ok {
+		return resp, *resp, true
+	}
+	for s.order.Len() >= IdempotencyKeys && s.order.Len() > 0 {
+		s.remove(s.order.Front().Value.(*idempotentResponse))
+	}
+	resp := &idempotentResponse{key: key, fingerprint: fingerprint}
+	resp.element = s.order.PushBack(resp)
+	s.responses[key] = resp
+	return resp, idempotentResponse{}, false
+}
+
+// finish records the response to the request that started resp, or forgets
+// the key if the response should not be replayed
+func (s *idempotencyStore) finish(resp *idempotentResponse, rec *responseRecorder, header http.Header) {
+	s.Lock()
+	defer s.Unlock()
+	if s.responses[resp.key] != resp {
+		// forgotten while in progress
+		return
+	}
+	if rec.status == 0 || rec.status >= 500 {
+		s.remove(resp)
+		return
+	}
+	resp.status = rec.status
+	resp.header = header
+	resp.body = rec.body.Bytes()
+	resp.expires = time.Now().Add(IdempotencyWindow)
+	resp.done = true
+}
+
+// remove forgets a response. It is called with the lock held.
+func (s *idempotencyStore) remove(resp *idempotentResponse) {
+	s.order.Remove(resp.element)
+	delete(s.responses, resp.key)
+}
+
+// responseRecorder writes a response through while recording it
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
+
+// Idempotent replays the first response to a POST, PUT, PATCH, or DELETE
+// request with an Idempotency-Key header to later requests with the same key,
+// for IdempotencyWindow, remembering up to IdempotencyKeys keys. Keys are
+// scoped to the Authorization header. Reusing a key for a different request is
+// rejected with 422, and reusing it while the first request is in progress is
+// rejected with 409. Server errors are not recorded, so the request can be
+// retried.
+func Idempotent(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Idempotency-Key")
+		switch {
+		case key == "":
+			h.ServeHTTP(w, r)
+			return
+		case r.Method != "POST" && r.Method != "PUT" && r.Method != "PATCH" && r.Method != "DELETE":
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		body, err := ioutil.ReadAll(io.LimitReader(r.Body, BodyLimit))
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, "Bad Request")
+			return
+		}
+		r.Body.Close()
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		key = helpers.GenerateSha1Hash(r.Header.Get("Authorization") + "\n" + key)
+		fingerprint := helpers.GenerateSha1Hash(r.Method + " " + r.URL.RequestURI() + "\n" + string(body))
+
+		resp, recorded, ok := idempotency.start(key, fingerprint, time.Now())
+		if ok {
+			w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+			switch {
+			case recorded.fingerprint != fingerprint:
+				w.WriteHeader(http.StatusUnprocessableEntity)
+				fmt.Fprint(w, "Idempotency key was used for a different request.")
+			case !recorded.done:
+				w.WriteHeader(http.StatusConflict)
+				fmt.Fprint(w, "A request with this idempotency key is in progress.")
+			default:
+				for k, v := range recorded.header {
+					w.Header()[k] = v
+				}
+				w.Header().Set("Idempotent-Replayed", "true")
+				w.WriteHeader(recorded.status)
+				w.Write(recorded.body)
+			}
+			return
+		}
+
+		rec := &responseRecorder{ResponseWriter: w}
+		before := w.Header().Clone()
+		defer func() {
+			// The body is recorded before Compress encodes it, so a
+			// replay is encoded again for its own Accept-Encoding
+			header := changedHeader(before, w.Header())
+			header.Del("Content-Encoding")
+			header.Del("Content-Length")
+			idempotency.finish(resp, rec, header)
+		}()
+		h.ServeHTTP(rec, r)
+	})
+}
+
+// changedHeader returns the fields of a header that were added or changed since
+// before, leaving out those the outer middleware set for the request, such as
+// X-Request-ID and the rate limit fields
+func changedHeader(before, after http.Header) http.Header {
+	header := http.Header{}
+	for k, v := range after {
+		if strings.Join(before[k], "\n") != strings.Join(v, "\n") {
+			header[k] = append([]string(nil), v...)
+		}
+	}
+	return header
+}
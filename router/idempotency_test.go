@@ -0,0 +1,166 @@
+// Package router contains endpoint information for the service.
+//
+// sn - https://github.com/sn
+package router
+
+import (
+	"compress/gzip"
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sn/service/user"
+)
+
+func TestIdempotent(t *testing.T) {
+	post := func(key, body string) (*http.Response, string) {
+		req, err := http.NewRequest("POST", server.URL+"/users", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Idempotency-Key", key)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		b, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp, string(b)
+	}
+
+	body := `{"username":"idempotent","password":"1@E4s67890","email":"idempotent@example.com"}`
+	count := len(user.GetAll())
+	first, firstBody := post("key-1", body)
+	if first.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d.", first.StatusCode)
+	}
+	second, secondBody := post("key-1", body)
+	if second.StatusCode != http.StatusCreated || secondBody != firstBody {
+		t.Error("Expected the first response to be replayed.")
+	}
+	if second.Header.Get("Idempotent-Replayed") != "true" {
+		t.Error("Expected replayed response to be marked.")
+	}
+	if len(user.GetAll()) != count+1 {
+		t.Error("Expected one user to be created.")
+	}
+
+	if resp, _ := post("key-1", strings.Replace(body, "idempotent", "different", -1)); resp.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("Expected 422 for reused key, got %d.", resp.StatusCode)
+	}
+	if resp, _ := post("key-2", body); resp.StatusCode != http.StatusConflict {
+		t.Errorf("Expected a new key to be handled, and the username to be taken, got %d.", resp.StatusCode)
+	}
+}
+
+func TestIdempotentRequestID(t *testing.T) {
+	body := `{"username":"requested","password":"1@E4s67890","email":"requested@example.com"}`
+	post := func(id string) *http.Response {
+		req, _ := http.NewRequest("POST", server.URL+"/v1/users", strings.NewReader(body))
+		req.Header.Set("Idempotency-Key", "request-id")
+		req.Header.Set("X-Request-ID", id)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	post("first-id")
+	resp := post("second-id")
+	if resp.Header.Get("Idempotent-Replayed") != "true" || resp.Header.Get("Content-Type") == "" {
+		t.Error("Expected the response to be replayed with the header of the handler.")
+	}
+	if id := resp.Header.Values("X-Request-ID"); len(id) != 1 || id[0] != "second-id" {
+		t.Errorf("Expected the replay to keep the ID of its own request, got %q.", id)
+	}
+}
+
+func TestIdempotentCompressed(t *testing.T) {
+	var ops []string
+	for i := 0; i < 20; i++ {
+		ops = append(ops, fmt.Sprintf(`{"op":"create","username":"replay%d","password":"1@E4s67890","email":"replay%d@example.com"}`, i, i))
+	}
+	body := `{"operations":[` + strings.Join(ops, ",") + `]}`
+	token := newAdmin(t, "replayer")
+	c := &http.Client{Transport: &http.Transport{DisableCompression: true}}
+	post := func(encoding string) string {
+		req, _ := http.NewRequest("POST", server.URL+"/users:batch", strings.NewReader(body))
+		req.Header.Set("Authorization", token)
+		req.Header.Set("Idempotency-Key", "compressed")
+		req.Header.Set("Accept-Encoding", encoding)
+		resp, err := c.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		r := io.Reader(resp.Body)
+		if resp.Header.Get("Content-Encoding") == "gzip" {
+			if r, err = gzip.NewReader(resp.Body); err != nil {
+				t.Fatalf("%s: expected a gzip body, got %v.", encoding, err)
+			}
+		}
+		b, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != http.StatusOK || !json.Valid(b) {
+			t.Errorf("%s: expected a JSON body, got %d %q.", encoding, resp.StatusCode, b)
+		}
+		return string(b)
+	}
+
+	first := post("gzip")
+	if replay := post("gzip"); replay != first {
+		t.Error("Expected the compressed response to be replayed.")
+	}
+	if replay := post("identity"); replay != first {
+		t.Error("Expected the response to be replayed uncompressed.")
+	}
+}
+
+func TestIdempotencyStore(t *testing.T) {
+	defer func(keys int) { IdempotencyKeys = keys }(IdempotencyKeys)
+	IdempotencyKeys = 2
+	s := &idempotencyStore{responses: map[string]*idempotentResponse{}, order: list.New()}
+	now := time.Now()
+	done := &responseRecorder{status: http.StatusOK}
+
+	a, _, _ := s.start("a", "", now)
+	s.finish(a, done, nil)
+	b, _, _ := s.start("b", "", now)
+	if _, _, ok := s.start("a", "", now); !ok {
+		t.Error("Expected the key to be remembered.")
+	}
+	s.start("c", "", now)
+	if _, ok := s.responses["a"]; ok || len(s.responses) != 2 || s.order.Len() != 2 {
+		t.Errorf("Expected the oldest key to be forgotten, got %d keys.", len(s.responses))
+	}
+	s.finish(b, done, nil)
+	if _, _, ok := s.start("b", "", now.Add(IdempotencyWindow+time.Minute)); ok {
+		t.Error("Expected the expired key to be forgotten.")
+	}
+
+	failed, _, _ := s.start("d", "", now)
+	s.finish(failed, &responseRecorder{status: http.StatusServiceUnavailable}, nil)
+	if _, ok := s.responses["d"]; ok {
+		t.Error("Expected the key of a server error to be forgotten.")
+	}
+
+	hung, _, _ := s.start("e", "", now)
+	f, _, _ := s.start("f", "", now)
+	s.finish(f, done, nil)
+	if _, _, ok := s.start("g", "", now.Add(IdempotencyWindow+time.Minute)); ok || s.responses["f"] != nil || s.responses["e"] != hung {
+		t.Error("Expected expired keys behind a request in progress to be forgotten.")
+	}
+}
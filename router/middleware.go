@@ -0,0 +1,245 @@
+// Package router contains endpoint information for the service.
+//
+// sn - https://github.com/sn
+package router
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"runtime/debug"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sn/service/helpers"
+	"github.com/sn/service/logging"
+	"github.com/sn/service/metrics"
+	"github.com/sn/service/session"
+	"github.com/sn/service/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// contextKey is the type of the request context keys set by the router
+type contextKey int
+
+const requestIDKey contextKey = iota
+
+// requestIDPattern matches the request IDs accepted from clients
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// RequestID tags each request with the X-Request-ID header of the request, or
+// a new UUID when the header is missing or invalid. The ID is added to the
+// request context and sent back in the X-Request-ID response header.
+func RequestID(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+		if !requestIDPattern.MatchString(id) {
+			id = string(helpers.GenerateUUID())
+		}
+		w.Header().Set("X-Request-ID", id)
+		h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey, id)))
+	})
+}
+
+// RequestIDFrom returns the request ID of a request context, or an empty
+// string if it has none
+func RequestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// routeTemplate returns the path template of the route matching a request, or
+// an empty string if no route matches
+func routeTemplate(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if template, err := route.GetPathTemplate(); err == nil {
+			return template
+		}
+	}
+	return ""
+}
+
+// AliasDeprecated is when the unversioned API paths were deprecated
+var AliasDeprecated = time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+
+// AliasSunset is when the unversioned API paths will be removed
+var AliasSunset = AliasDeprecated.AddDate(0, 6, 0)
+
+// Deprecated marks responses to the unversioned API paths as deprecated with
+// the Deprecation and Sunset headers. The Link header is left to handlers, as
+// clients reading pagination links may only read its first value.
+func Deprecated(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", fmt.Sprintf("@%d", AliasDeprecated.Unix()))
+		w.Header().Set("Sunset", AliasSunset.UTC().Format(http.TimeFormat))
+		h.ServeHTTP(w, r)
+	})
+}
+
+// Trace starts a server span for each request, continuing the trace of the
+// W3C traceparent header if any. The span is named after the method and
+// route template, and ends with the response status.
+func Trace(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		route := routeTemplate(r)
+		name := r.Method
+		if route != "" {
+			name += " " + route
+		}
+		ctx, span := tracing.Start(ctx, name, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(
+			attribute.String("http.request.method", r.Method),
+			attribute.String("http.route", route),
+			attribute.String("url.path", r.URL.Path),
+			attribute.String("request.id", RequestIDFrom(r.Context())),
+		))
+		defer span.End()
+
+		sw := &statusWriter{ResponseWriter: w}
+		h.ServeHTTP(sw, r.WithContext(ctx))
+		if sw.status == 0 {
+			sw.status = http.StatusOK
+		}
+		span.SetAttributes(attribute.Int("http.response.status_code", sw.status))
+		if sw.status >= 500 {
+			span.SetStatus(codes.Error, http.StatusText(sw.status))
+		}
+	})
+}
+
+// Log carries a logger with the request ID, route, user ID, and trace in the
+// request context, and logs each request when it completes. Headers are logged at the
+// debug level, with credentials redacted.
+func Log(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		l := slog.Default().With("request_id", RequestIDFrom(r.Context()), "method", r.Method, "path", r.URL.Path)
+		if route := routeTemplate(r); route != "" {
+			l = l.With("route", route)
+		}
+		if sc := trace.SpanContextFromContext(r.Context()); sc.IsValid() {
+			l = l.With("trace_id", sc.TraceID().String(), "span_id", sc.SpanID().String())
+		}
+		if auth := r.Header.Get("Authorization"); auth != "" {
+			if s := session.Find(r.Context(), auth); s.ID != "" {
+				l = l.With("user_id", s.UserID)
+			}
+		}
+
+		sw := &statusWriter{ResponseWriter: w}
+		h.ServeHTTP(sw, r.WithContext(logging.NewContext(r.Context(), l)))
+
+		if sw.status == 0 {
+			sw.status = http.StatusOK
+		}
+		attrs := []interface{}{"status", sw.status, "bytes", sw.bytes, "duration", time.Since(start), "remote_addr", r.RemoteAddr, "user_agent", r.UserAgent()}
+		if l.Enabled(r.Context(), slog.LevelDebug) {
+			attrs = append(attrs, "headers", logging.Headers(r.Header))
+		}
+		l.Info("request", attrs...)
+	})
+}
+
+// Instrument records the count and latency of requests by method, route
+// template, and status. Requests not matching a route are recorded with the
+// route "unmatched".
+func Instrument(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		route := routeTemplate(r)
+		if route == "" {
+			route = "unmatched"
+		}
+		sw := &statusWriter{ResponseWriter: w}
+		h.ServeHTTP(sw, r)
+		if sw.status == 0 {
+			sw.status = http.StatusOK
+		}
+		status := strconv.Itoa(sw.status)
+		metrics.Requests.WithLabelValues(r.Method, route, status).Inc()
+		metrics.RequestDuration.WithLabelValues(r.Method, route, status).Observe(time.Since(start).Seconds())
+	})
+}
+
+// statusWriter records the status and size of a response
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (sw *statusWriter) WriteHeader(status int) {
+	if sw.status == 0 {
+		sw.status = status
+	}
+	sw.ResponseWriter.WriteHeader(status)
+}
+
+func (sw *statusWriter) Write(b []byte) (int, error) {
+	if sw.status == 0 {
+		sw.status = http.StatusOK
+	}
+	n, err := sw.ResponseWriter.Write(b)
+	sw.bytes += n
+	return n, err
+}
+
+// Recover responds with 500 when a handler panics, and logs the panic with
+// its stack instead of crashing the server
+func Recover(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sw := &statusWriter{ResponseWriter: w}
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			if v == http.ErrAbortHandler {
+				panic(v)
+			}
+			logging.FromContext(r.Context()).Error("panic", "error", fmt.Sprint(v), "stack", string(debug.Stack()))
+			if sw.status == 0 {
+				internalError(sw)
+			}
+		}()
+		h.ServeHTTP(sw, r)
+	})
+}
+
+// handler adapts a function returning an error to an http.Handler. A
+// *statusError is sent to the client with its status. Any other error, such
+// as failing to write the response, is logged, and sent as a 500 if the
+// response has not been started.
+func handler(fn func(w http.ResponseWriter, r *http.Request) error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sw := &statusWriter{ResponseWriter: w}
+		err := fn(sw, r)
+		if err == nil {
+			return
+		}
+		if serr, ok := err.(*statusError); ok && sw.status == 0 {
+			w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+			w.WriteHeader(serr.status)
+			fmt.Fprint(w, serr.message)
+			return
+		}
+		logging.FromContext(r.Context()).Error("handler failed", "error", err)
+		if sw.status == 0 {
+			internalError(w)
+		}
+	})
+}
+
+// internalError responds with 500
+func internalError(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusInternalServerError)
+	fmt.Fprint(w, "Internal Server Error")
+}
@@ -0,0 +1,258 @@
+// Package router contains endpoint information for the service.
+//
+// sn - https://github.com/sn
+package router
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sn/service/logging"
+	"github.com/sn/service/user"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestRequestID(t *testing.T) {
+	var got string
+	h := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = RequestIDFrom(r.Context())
+	}))
+
+	tests := []struct {
+		header string
+		keep   bool
+	}{
+		{"abc-123", true},
+		{"", false},
+		{"has space", false},
+		{strings.Repeat("a", 129), false},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest("GET", "/", nil)
+		if test.header != "" {
+			req.Header.Set("X-Request-ID", test.header)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if got == "" || rec.Header().Get("X-Request-ID") != got {
+			t.Errorf("%q: expected the response ID to match the context ID.", test.header)
+		}
+		if (got == test.header) != test.keep {
+			t.Errorf("%q: expected keeping the ID to be %v.", test.header, test.keep)
+		}
+	}
+}
+
+// captureLogs sets the default logger to log to the returned buffer until the
+// test ends
+func captureLogs(t *testing.T, level string) *bytes.Buffer {
+	var buf bytes.Buffer
+	l, err := logging.New(&buf, level, logging.FormatJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prev := slog.Default()
+	slog.SetDefault(l)
+	t.Cleanup(func() { slog.SetDefault(prev) })
+	return &buf
+}
+
+func TestLog(t *testing.T) {
+	logs := captureLogs(t, "debug")
+	u := user.FindByUsername(context.Background(), "alex")
+	token, err := getAuthToken(user.User{ID: u.ID, Password: "1@E4s67890"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := http.NewRequest("GET", server.URL+"/users/"+string(u.ID), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", token)
+	req.Header.Set("X-Request-ID", "log-test")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	var entry struct {
+		Msg       string
+		RequestID string `json:"request_id"`
+		Route     string
+		UserID    string `json:"user_id"`
+		Status    int
+		Headers   map[string]string
+	}
+	for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatal(err)
+		}
+		if entry.RequestID == "log-test" && entry.Msg == "request" {
+			break
+		}
+	}
+	if entry.RequestID != "log-test" || entry.Route != "/users/{userId}" || entry.UserID != string(u.ID) || entry.Status != http.StatusOK {
+		t.Errorf("Expected request ID, route, user ID, and status to be logged, got %+v.", entry)
+	}
+	if entry.Headers["Authorization"] != logging.Redacted || strings.Contains(logs.String(), token) {
+		t.Error("Expected the Authorization header to be redacted.")
+	}
+}
+
+func TestInstrument(t *testing.T) {
+	u := user.FindByUsername(context.Background(), "alex")
+	for _, path := range []string{"/users/" + string(u.ID), "/unknown"} {
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if _, err := getAuthToken(user.User{ID: u.ID, Password: "wrong"}); err == nil {
+		t.Error("Expected wrong password to fail.")
+	}
+
+	resp, err := http.Get(server.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		`sn_http_requests_total{method="GET",route="/users/{userId}",status="200"}`,
+		`sn_http_requests_total{method="GET",route="unmatched",status="404"}`,
+		`sn_http_request_duration_seconds_bucket{method="GET",route="/users/{userId}",status="200",le="+Inf"}`,
+		`sn_auth_attempts_total{result="failure"}`,
+		"sn_sessions_active",
+		"sn_users",
+		"sn_password_hash_duration_seconds_count",
+		"go_memstats_alloc_bytes",
+	} {
+		if !strings.Contains(string(body), s) {
+			t.Errorf("Expected metrics to contain %q.", s)
+		}
+	}
+}
+
+func TestTrace(t *testing.T) {
+	defer otel.SetTracerProvider(otel.GetTracerProvider())
+	exporter := tracetest.NewInMemoryExporter()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	u := user.FindByUsername(context.Background(), "alex")
+	body := `{"ID":"` + string(u.ID) + `","Password":"1@E4s67890"}`
+	req, err := http.NewRequest("POST", server.URL+"/auth", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	spans := map[string]tracetest.SpanStub{}
+	for _, span := range exporter.GetSpans() {
+		spans[span.Name] = span
+	}
+	root, ok := spans["POST /auth"]
+	if !ok {
+		t.Fatalf("Expected a server span for the route, got %v.", spans)
+	}
+	if root.SpanContext.TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" || root.Parent.SpanID().String() != "00f067aa0ba902b7" {
+		t.Error("Expected the server span to continue the traceparent.")
+	}
+	for _, name := range []string{"user.FindByID", "helpers.GeneratePasswordHash", "session.Create"} {
+		span, ok := spans[name]
+		if !ok {
+			t.Errorf("Expected a %s span.", name)
+			continue
+		}
+		if span.SpanContext.TraceID() != root.SpanContext.TraceID() {
+			t.Errorf("Expected the %s span to be in the request trace.", name)
+		}
+	}
+	if spans["user.FindByID"].Parent.SpanID() != root.SpanContext.SpanID() {
+		t.Error("Expected store spans to be children of the server span.")
+	}
+}
+
+func TestRecover(t *testing.T) {
+	logs := captureLogs(t, "info")
+	h := RequestID(Log(Recover(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))))
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Request-ID", "recover-test")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status 500, got %d.", rec.Code)
+	}
+	for _, s := range []string{"recover-test", "boom", "middleware_test.go"} {
+		if !strings.Contains(logs.String(), s) {
+			t.Errorf("Expected the log to contain %q.", s)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	logs := captureLogs(t, "info")
+	tests := []struct {
+		fn     func(w http.ResponseWriter, r *http.Request) error
+		status int
+		body   string
+	}{
+		{func(w http.ResponseWriter, r *http.Request) error { return nil }, http.StatusOK, ""},
+		{func(w http.ResponseWriter, r *http.Request) error { return &statusError{http.StatusTeapot, "Teapot."} }, http.StatusTeapot, "Teapot."},
+		{func(w http.ResponseWriter, r *http.Request) error { return errors.New("broken") }, http.StatusInternalServerError, "Internal Server Error"},
+		{func(w http.ResponseWriter, r *http.Request) error {
+			w.WriteHeader(http.StatusAccepted)
+			return errors.New("broken")
+		}, http.StatusAccepted, ""},
+	}
+	for i, test := range tests {
+		rec := httptest.NewRecorder()
+		handler(test.fn).ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+		if rec.Code != test.status || rec.Body.String() != test.body {
+			t.Errorf("%d: expected %d %q, got %d %q.", i, test.status, test.body, rec.Code, rec.Body.String())
+		}
+	}
+	if strings.Count(logs.String(), "broken") != 2 {
+		t.Errorf("Expected errors to be logged, got %q.", logs.String())
+	}
+}
+
+// failingReader fails every read
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, errors.New("connection reset")
+}
+
+func TestReadBodyError(t *testing.T) {
+	req := httptest.NewRequest("POST", "/users", failingReader{})
+	rec := httptest.NewRecorder()
+	UserCreate.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d.", rec.Code)
+	}
+}
@@ -0,0 +1,368 @@
+// Package router contains endpoint information for the service.
+//
+// sn - https://github.com/sn
+package router
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/mail"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+	"github.com/sn/service/codec"
+	"github.com/sn/service/types"
+)
+
+// specJSON is the OpenAPI 3 document describing the routes of the router
+//
+//go:embed openapi.json
+var specJSON []byte
+
+// spec is the parsed OpenAPI document, holding what requests are validated
+// against
+var spec = parseSpec(specJSON)
+
+// openAPI is the part of an OpenAPI document used to validate requests
+type openAPI struct {
+	Paths      map[string]map[string]*operation `json:"paths"`
+	Components struct {
+		Parameters map[string]*parameter `json:"parameters"`
+		Schemas    map[string]*schema    `json:"schemas"`
+	} `json:"components"`
+}
+
+// operation is an OpenAPI operation: a method of a path
+type operation struct {
+	OperationID string       `json:"operationId"`
+	Parameters  []*parameter `json:"parameters"`
+	RequestBody *struct {
+		Required bool `json:"required"`
+		Content  map[string]struct {
+			Schema *schema `json:"schema"`
+		} `json:"content"`
+	} `json:"requestBody"`
+}
+
+// parameter is an OpenAPI parameter, or a reference to one
+type parameter struct {
+	Ref      string  `json:"$ref"`
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *schema `json:"schema"`
+}
+
+// schema is an OpenAPI schema, or a reference to one. Only the keywords used
+// by the document are supported.
+type schema struct {
+	Ref        string             `json:"$ref"`
+	Type       string             `json:"type"`
+	Format     string             `json:"format"`
+	Nullable   bool               `json:"nullable"`
+	Enum       []interface{}      `json:"enum"`
+	Properties map[string]*schema `json:"properties"`
+	Required   []string           `json:"required"`
+	Items      *schema            `json:"items"`
+	MinLength  *int               `json:"minLength"`
+	MaxLength  *int               `json:"maxLength"`
+	Minimum    *float64           `json:"minimum"`
+	Maximum    *float64           `json:"maximum"`
+}
+
+// parseSpec parses an OpenAPI document, panicking if it is malformed
+func parseSpec(b []byte) *openAPI {
+	s := &openAPI{}
+	if err := json.Unmarshal(b, s); err != nil {
+		panic(fmt.Sprintf("router: invalid openapi.json: %v", err))
+	}
+	return s
+}
+
+// OpenAPI handles GET /openapi.json
+var OpenAPI = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(specJSON)
+})
+
+// operation returns the operation for a method and route template, or nil if
+// the document does not describe it. The unversioned aliases are described by
+// the version they alias.
+func (s *openAPI) operation(method, template string) *operation {
+	method = strings.ToLower(method)
+	if op := s.Paths[template][method]; op != nil {
+		return op
+	}
+	return s.Paths[aliasVersion+template][method]
+}
+
+// parameter resolves a parameter reference
+func (s *openAPI) parameter(p *parameter) *parameter {
+	if p.Ref != "" {
+		return s.Components.Parameters[strings.TrimPrefix(p.Ref, "#/components/parameters/")]
+	}
+	return p
+}
+
+// schema resolves a schema reference
+func (s *openAPI) schema(sc *schema) *schema {
+	for sc != nil && sc.Ref != "" {
+		sc = s.Components.Schemas[strings.TrimPrefix(sc.Ref, "#/components/schemas/")]
+	}
+	return sc
+}
+
+// ValidateRequest rejects requests whose parameters or body do not match the
+// OpenAPI document with 400. Bodies of media types neither the document nor a
+// codec describes are left to the handler.
+func ValidateRequest(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		op := spec.operation(r.Method, routeTemplate(r))
+		if op == nil {
+			h.ServeHTTP(w, r)
+			return
+		}
+		if err := spec.validateRequest(op, r); err != nil {
+			w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, err)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// validateRequest validates the parameters and body of a request
+func (s *openAPI) validateRequest(op *operation, r *http.Request) error {
+	query := r.URL.Query()
+	for _, p := range op.Parameters {
+		p = s.parameter(p)
+		var value string
+		var ok bool
+		switch p.In {
+		case "path":
+			value, ok = mux.Vars(r)[p.Name]
+		case "query":
+			if _, ok = query[p.Name]; ok {
+				value = query.Get(p.Name)
+			}
+		case "header":
+			value = r.Header.Get(p.Name)
+			ok = value != ""
+		}
+		if !ok {
+			if p.Required {
+				return fmt.Errorf("%s is required.", p.Name)
+			}
+			continue
+		}
+		if err := s.validateParameter(value, p); err != nil {
+			return err
+		}
+	}
+
+	if op.RequestBody == nil || (r.Method != "POST" && r.Method != "PUT" && r.Method != "PATCH") {
+		return nil
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, BodyLimit))
+	if err != nil {
+		return fmt.Errorf("Unable to read body.")
+	}
+	r.Body.Close()
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if len(bytes.TrimSpace(body)) == 0 {
+		if op.RequestBody.Required {
+			return fmt.Errorf("Request body is required.")
+		}
+		return nil
+	}
+	mediaType := "application/json"
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		if mediaType, _, err = mime.ParseMediaType(contentType); err != nil {
+			return nil
+		}
+	}
+	content, ok := op.RequestBody.Content[mediaType]
+	if c, err := codec.ForContentType(mediaType); !ok && err == nil && c != codec.JSON {
+		// MessagePack and CBOR bodies are validated as the JSON they
+		// transcode to
+		var v interface{}
+		if err := c.Unmarshal(body, &v); err != nil {
+			return fmt.Errorf("Request body is not valid %s.", c.MediaType())
+		}
+		if body, err = json.Marshal(v); err != nil {
+			return fmt.Errorf("Request body cannot be represented as JSON.")
+		}
+		content, ok = op.RequestBody.Content["application/json"]
+	}
+	if !ok || content.Schema == nil {
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return fmt.Errorf("Request body is not valid JSON.")
+	}
+	return s.validate(doc, content.Schema, "")
+}
+
+// validateParameter validates a parameter value, converting it to the type of
+// the parameter's schema
+func (s *openAPI) validateParameter(value string, p *parameter) error {
+	sc := s.schema(p.Schema)
+	if sc == nil {
+		return nil
+	}
+	var v interface{} = value
+	switch sc.Type {
+	case "integer", "number":
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return fmt.Errorf("%s must be a number.", p.Name)
+		}
+		v = json.Number(value)
+	case "boolean":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%s must be true or false.", p.Name)
+		}
+		v = b
+	}
+	return s.validate(v, sc, p.Name)
+}
+
+// validate validates a decoded JSON value against a schema. The name locates
+// the value in error messages.
+func (s *openAPI) validate(v interface{}, sc *schema, name string) error {
+	sc = s.schema(sc)
+	if sc == nil {
+		return nil
+	}
+	label := name
+	if label == "" {
+		label = "Request body"
+	}
+	if v == nil {
+		if sc.Nullable || sc.Type == "" {
+			return nil
+		}
+		return fmt.Errorf("%s must not be null.", label)
+	}
+	if len(sc.Enum) > 0 {
+		found := false
+		for _, e := range sc.Enum {
+			if fmt.Sprint(e) == fmt.Sprint(v) {
+				found = true
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s must be one of %v.", label, sc.Enum)
+		}
+	}
+
+	switch sc.Type {
+	case "object":
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s must be an object.", label)
+		}
+		for _, field := range sc.Required {
+			if _, ok := obj[field]; !ok {
+				return fmt.Errorf("%s is required.", child(name, field))
+			}
+		}
+		fields := make([]string, 0, len(sc.Properties))
+		for field := range sc.Properties {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		for _, field := range fields {
+			if value, ok := obj[field]; ok {
+				if err := s.validate(value, sc.Properties[field], child(name, field)); err != nil {
+					return err
+				}
+			}
+		}
+	case "array":
+		items, ok := v.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s must be an array.", label)
+		}
+		for i, item := range items {
+			if err := s.validate(item, sc.Items, fmt.Sprintf("%s[%d]", name, i)); err != nil {
+				return err
+			}
+		}
+	case "string":
+		str, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("%s must be a string.", label)
+		}
+		if sc.MinLength != nil && utf8.RuneCountInString(str) < *sc.MinLength {
+			return fmt.Errorf("%s must be at least %d characters long.", label, *sc.MinLength)
+		}
+		if sc.MaxLength != nil && utf8.RuneCountInString(str) > *sc.MaxLength {
+			return fmt.Errorf("%s must be at most %d characters long.", label, *sc.MaxLength)
+		}
+		return validateFormat(str, sc.Format, label)
+	case "integer", "number":
+		n, ok := v.(json.Number)
+		if !ok {
+			return fmt.Errorf("%s must be a number.", label)
+		}
+		f, err := n.Float64()
+		if err != nil {
+			return fmt.Errorf("%s must be a number.", label)
+		}
+		if _, err := n.Int64(); sc.Type == "integer" && err != nil {
+			return fmt.Errorf("%s must be an integer.", label)
+		}
+		if sc.Minimum != nil && f < *sc.Minimum {
+			return fmt.Errorf("%s must be at least %v.", label, *sc.Minimum)
+		}
+		if sc.Maximum != nil && f > *sc.Maximum {
+			return fmt.Errorf("%s must be at most %v.", label, *sc.Maximum)
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return fmt.Errorf("%s must be true or false.", label)
+		}
+	}
+	return nil
+}
+
+// validateFormat validates a string of a format
+func validateFormat(s, format, name string) error {
+	var err error
+	switch format {
+	case "uuid":
+		err = types.UUID(s).Validate()
+	case "date-time":
+		_, err = time.Parse(time.RFC3339, s)
+	case "email":
+		_, err = mail.ParseAddress(s)
+	}
+	if err != nil {
+		return fmt.Errorf("%s must be a valid %s.", name, format)
+	}
+	return nil
+}
+
+// child names a field of a value in error messages
+func child(name, field string) string {
+	if name == "" {
+		return field
+	}
+	return name + "." + field
+}
@@ -0,0 +1,171 @@
+// Package router contains endpoint information for the service.
+//
+// sn - https://github.com/sn
+package router
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/sn/service/helpers"
+)
+
+func TestSpecDescribesRoutes(t *testing.T) {
+	router := NewRouter()
+	err := router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		template, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil
+		}
+		for _, method := range methods {
+			if spec.operation(method, template) == nil {
+				t.Errorf("%s %s is not described in openapi.json.", method, template)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for path, item := range spec.Paths {
+		for method := range item {
+			req := httptest.NewRequest(strings.ToUpper(method), strings.Replace(path, "{userId}", string(helpers.GenerateUUID()), 1), nil)
+			var match mux.RouteMatch
+			if !router.Match(req, &match) || match.MatchErr != nil {
+				t.Errorf("%s %s is described in openapi.json but not routed.", method, path)
+			}
+		}
+	}
+}
+
+// refs returns the references in a JSON document
+func refs(v interface{}) []string {
+	var found []string
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if ref, ok := value.(string); ok && key == "$ref" {
+				found = append(found, ref)
+			}
+			found = append(found, refs(value)...)
+		}
+	case []interface{}:
+		for _, value := range v {
+			found = append(found, refs(value)...)
+		}
+	}
+	return found
+}
+
+func TestSpecReferences(t *testing.T) {
+	var doc interface{}
+	if err := json.Unmarshal(specJSON, &doc); err != nil {
+		t.Fatal(err)
+	}
+	for _, ref := range refs(doc) {
+		target := doc
+		for _, key := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+			obj, ok := target.(map[string]interface{})
+			if !ok {
+				target = nil
+				break
+			}
+			target = obj[key]
+		}
+		if target == nil {
+			t.Errorf("Unresolved reference %q.", ref)
+		}
+	}
+}
+
+func TestOpenAPI(t *testing.T) {
+	resp, err := http.Get(server.URL + "/openapi.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		OpenAPI string `json:"openapi"`
+	}
+	if resp.StatusCode != http.StatusOK || json.Unmarshal(body, &doc) != nil || doc.OpenAPI != "3.0.3" {
+		t.Errorf("Expected the OpenAPI document, got %d.", resp.StatusCode)
+	}
+}
+
+func TestValidateRequest(t *testing.T) {
+	router := NewRouter()
+	id := string(helpers.GenerateUUID())
+
+	tests := []struct {
+		method      string
+		path        string
+		contentType string
+		body        string
+		message     string
+	}{
+		{"GET", "/v1/users?limit=x", "", "", "limit must be a number."},
+		{"GET", "/v1/users?limit=1.5", "", "", "limit must be an integer."},
+		{"GET", "/v1/users?limit=0", "", "", "limit must be at least 1."},
+		{"GET", "/v1/users?sort=password", "", "", "sort must be one of [created -created username -username]."},
+		{"GET", "/users?created_after=yesterday", "", "", "created_after must be a valid date-time."},
+		{"GET", "/v1/users/garbage", "", "", "userId must be a valid uuid."},
+		{"POST", "/v1/users", "", "", "Request body is required."},
+		{"POST", "/v1/users", "", "{", "Request body is not valid JSON."},
+		{"POST", "/v1/users", "", "[]", "Request body must be an object."},
+		{"POST", "/v1/users", "", `{"username":"emery","password":"1@E4s67890"}`, "email is required."},
+		{"POST", "/v1/users", "", `{"username":1,"password":"1@E4s67890","email":"emery@example.com"}`, "username must be a string."},
+		{"POST", "/users", "application/json; charset=UTF-8", `{"username":"emery","password":"short","email":"emery@example.com"}`, "password must be at least 10 characters long."},
+		{"POST", "/v1/users", "", `{"username":"emery","password":"1@E4s67890","email":"emery"}`, "email must be a valid email."},
+		{"POST", "/v1/auth", "", `{"ID":"garbage"}`, "ID must be a valid uuid."},
+		{"POST", "/v1/users:batch", "", `{"operations":[{"id":"garbage"}]}`, "operations[0].op is required."},
+		{"PATCH", "/v1/users/" + id, MergePatchType, `{"username":1}`, "username must be a string."},
+		{"PATCH", "/v1/users/" + id, JSONPatchType, `[{"op":"rename","path":"/username"}]`, "[0].op must be one of [add remove replace move copy test]."},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(test.method, test.path, strings.NewReader(test.body))
+		if test.contentType != "" {
+			req.Header.Set("Content-Type", test.contentType)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != http.StatusBadRequest || rec.Body.String() != test.message {
+			t.Errorf("%s %s %s: expected 400 %q, got %d %q.", test.method, test.path, test.body, test.message, rec.Code, rec.Body.String())
+		}
+	}
+
+	valid := []struct {
+		method      string
+		path        string
+		contentType string
+		body        string
+	}{
+		{"GET", "/v1/users?limit=10&sort=-username&created_after=2020-01-01T00:00:00Z", "", ""},
+		{"PATCH", "/v1/users/" + id, MergePatchType, `{"email":null}`},
+		{"PATCH", "/v1/users/" + id, "text/plain", `username=patched`},
+	}
+	for _, test := range valid {
+		req := httptest.NewRequest(test.method, test.path, strings.NewReader(test.body))
+		if test.contentType != "" {
+			req.Header.Set("Content-Type", test.contentType)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code == http.StatusBadRequest {
+			t.Errorf("%s %s %s: expected the request to be valid, got %q.", test.method, test.path, test.body, rec.Body.String())
+		}
+	}
+}
@@ -0,0 +1,167 @@
+// Package router contains endpoint information for the service.
+//
+// sn - https://github.com/sn
+package router
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/sn/service/user"
+)
+
+// Patch document media types
+const (
+	MergePatchType = "application/merge-patch+json" // RFC 7396
+	JSONPatchType  = "application/json-patch+json"  // RFC 6902
+)
+
+// patchFields are the patchable user fields, keyed by their JSON name.
+// Write-only fields can be replaced but not tested or read. Fields with a
+// default are reset to it when removed; the others cannot be removed.
+var patchFields = map[string]struct {
+	writeOnly bool
+	def       string
+}{
+	"username": {},
+	"email":    {},
+	"password": {writeOnly: true},
+	"role":     {def: user.RoleUser},
+}
+
+// badPatch returns an error for a malformed patch document
+func badPatch(format string, a ...interface{}) error {
+	return &statusError{http.StatusBadRequest, fmt.Sprintf(format, a...)}
+}
+
+// patchOperation is a JSON Patch operation
+type patchOperation struct {
+	Op    string           `json:"op"`
+	Path  string           `json:"path"`
+	Value *json.RawMessage `json:"value"`
+}
+
+// patchDocument returns the patchable representation of a user
+func patchDocument(u user.User) map[string]string {
+	doc := map[string]string{"username": u.Username, "role": u.Role}
+	if u.Address != nil {
+		doc["email"] = u.Address.Address
+	}
+	return doc
+}
+
+// applyPatch applies a patch document of the given content type to a user and
+// returns the fields it sets. Plain JSON is treated as a merge patch.
+func applyPatch(contentType string, body []byte, u user.User) (map[string]string, error) {
+	mediaType := MergePatchType
+	if contentType != "" {
+		t, _, err := mime.ParseMediaType(contentType)
+		if err != nil {
+			return nil, &statusError{http.StatusUnsupportedMediaType, "Unsupported media type."}
+		}
+		mediaType = t
+	}
+	switch mediaType {
+	case MergePatchType, "application/json":
+		return applyMergePatch(body)
+	case JSONPatchType:
+		return applyJSONPatch(body, patchDocument(u))
+	}
+	return nil, &statusError{http.StatusUnsupportedMediaType, "Unsupported media type."}
+}
+
+// applyMergePatch returns the fields set by a JSON Merge Patch document. A
+// null field is reset to its default.
+func applyMergePatch(body []byte) (map[string]string, error) {
+	var patch map[string]*json.RawMessage
+	if err := json.Unmarshal(body, &patch); err != nil || patch == nil {
+		return nil, badPatch("Merge patch must be a JSON object.")
+	}
+	fields := map[string]string{}
+	for name, raw := range patch {
+		field, ok := patchFields[name]
+		if !ok {
+			return nil, badPatch("Unknown field %q.", name)
+		}
+		if raw == nil {
+			if field.def == "" {
+				return nil, &statusError{http.StatusUnprocessableEntity, fmt.Sprintf("Field %q cannot be removed.", name)}
+			}
+			fields[name] = field.def
+			continue
+		}
+		var value string
+		if err := json.Unmarshal(*raw, &value); err != nil {
+			return nil, badPatch("Field %q must be a string.", name)
+		}
+		fields[name] = value
+	}
+	return fields, nil
+}
+
+// applyJSONPatch applies a JSON Patch document to doc and returns the fields
+// set by it. Operations are applied in order, and none are applied if any
+// fails. The test, add, replace, and remove operations are supported; only
+// fields with a default can be removed.
+func applyJSONPatch(body []byte, doc map[string]string) (map[string]string, error) {
+	var ops []patchOperation
+	if err := json.Unmarshal(body, &ops); err != nil {
+		return nil, badPatch("JSON patch must be an array of operations.")
+	}
+	fields := map[string]string{}
+	for i, op := range ops {
+		name, err := patchPath(op.Path)
+		if err != nil {
+			return nil, err
+		}
+		field, ok := patchFields[name]
+		if !ok {
+			return nil, badPatch("Operation %d: unknown path %q.", i, op.Path)
+		}
+
+		var value string
+		switch op.Op {
+		case "test", "add", "replace":
+			if op.Value == nil {
+				return nil, badPatch("Operation %d: missing value.", i)
+			}
+			if err := json.Unmarshal(*op.Value, &value); err != nil {
+				return nil, badPatch("Operation %d: value must be a string.", i)
+			}
+		}
+
+		switch op.Op {
+		case "test":
+			if field.writeOnly {
+				return nil, badPatch("Operation %d: %q cannot be tested.", i, op.Path)
+			}
+			if current, ok := doc[name]; !ok || current != value {
+				return nil, &statusError{http.StatusConflict, fmt.Sprintf("Operation %d: test failed for %q.", i, op.Path)}
+			}
+		case "add", "replace":
+			doc[name] = value
+			fields[name] = value
+		case "remove":
+			if field.def == "" {
+				return nil, &statusError{http.StatusUnprocessableEntity, fmt.Sprintf("Operation %d: %q cannot be removed.", i, op.Path)}
+			}
+			delete(doc, name)
+			fields[name] = field.def
+		default:
+			return nil, badPatch("Operation %d: unsupported op %q.", i, op.Op)
+		}
+	}
+	return fields, nil
+}
+
+// patchPath returns the member name referenced by a JSON pointer
+func patchPath(pointer string) (string, error) {
+	if !strings.HasPrefix(pointer, "/") || strings.Count(pointer, "/") != 1 {
+		return "", badPatch("Invalid path %q.", pointer)
+	}
+	name := strings.Replace(pointer[1:], "~1", "/", -1)
+	return strings.Replace(name, "~0", "~", -1), nil
+}
@@ -0,0 +1,168 @@
+// Package router contains endpoint information for the service.
+//
+// sn - https://github.com/sn
+package router
+
+import (
+	"context"
+	"net/http"
+	"net/mail"
+	"strings"
+	"testing"
+
+	"github.com/sn/service/user"
+)
+
+func TestApplyMergePatch(t *testing.T) {
+	fields, err := applyMergePatch([]byte(`{"username":"alex"}`))
+	if err != nil {
+		t.Error(err)
+	}
+	if len(fields) != 1 || fields["username"] != "alex" {
+		t.Error("Expected only username to be set.")
+	}
+
+	bad := map[string]int{
+		`[]`:                http.StatusBadRequest,
+		`{"version":"2"}`:   http.StatusBadRequest,
+		`{"username":1}`:    http.StatusBadRequest,
+		`{"email":null}`:    http.StatusUnprocessableEntity,
+		`{"password":null}`: http.StatusUnprocessableEntity,
+	}
+	for body, status := range bad {
+		_, err := applyMergePatch([]byte(body))
+		if err == nil || err.(*statusError).status != status {
+			t.Errorf("%s: expected status %d, got %v.", body, status, err)
+		}
+	}
+}
+
+func TestApplyJSONPatch(t *testing.T) {
+	doc := map[string]string{"username": "alex", "email": "alex@example.com"}
+	fields, err := applyJSONPatch([]byte(`[
+		{"op":"test","path":"/username","value":"alex"},
+		{"op":"replace","path":"/username","value":"blake"},
+		{"op":"test","path":"/username","value":"blake"}
+	]`), doc)
+	if err != nil {
+		t.Error(err)
+	}
+	if len(fields) != 1 || fields["username"] != "blake" {
+		t.Error("Expected only username to be set.")
+	}
+	doc["role"] = user.RoleAdmin
+	if fields, err := applyJSONPatch([]byte(`[{"op":"remove","path":"/role"}]`), doc); err != nil || fields["role"] != user.RoleUser {
+		t.Errorf("Expected removing the role to reset it, got %v, %v.", fields, err)
+	}
+
+	bad := map[string]int{
+		`{}`: http.StatusBadRequest,
+		`[{"op":"test","path":"/username","value":"corey"}]`:   http.StatusConflict,
+		`[{"op":"test","path":"/password","value":"secret"}]`:  http.StatusBadRequest,
+		`[{"op":"remove","path":"/email"}]`:                    http.StatusUnprocessableEntity,
+		`[{"op":"replace","path":"/version","value":"2"}]`:     http.StatusBadRequest,
+		`[{"op":"replace","path":"username","value":"corey"}]`: http.StatusBadRequest,
+		`[{"op":"replace","path":"/username"}]`:                http.StatusBadRequest,
+		`[{"op":"move","from":"/email","path":"/username"}]`:   http.StatusBadRequest,
+	}
+	for body, status := range bad {
+		_, err := applyJSONPatch([]byte(body), doc)
+		if err == nil || err.(*statusError).status != status {
+			t.Errorf("%s: expected status %d, got %v.", body, status, err)
+		}
+	}
+}
+
+func TestUserPatch(t *testing.T) {
+	addr, err := mail.ParseAddress("patch@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	u := user.Create(context.Background(), user.User{Username: "patch", Password: "1@E4s67890", Address: addr})
+
+	tests := []struct {
+		contentType string
+		body        string
+		status      int
+	}{
+		{MergePatchType, `{"username":"patched"}`, http.StatusOK},
+		{MergePatchType, `{"username":""}`, http.StatusBadRequest},
+		{MergePatchType, `{"email":null}`, http.StatusUnprocessableEntity},
+		{JSONPatchType, `[{"op":"test","path":"/username","value":"patched"},{"op":"replace","path":"/email","value":"patched@example.com"}]`, http.StatusOK},
+		{JSONPatchType, `[{"op":"test","path":"/username","value":"patch"}]`, http.StatusConflict},
+		{JSONPatchType, `[{"op":"remove","path":"/username"}]`, http.StatusUnprocessableEntity},
+		{"text/plain", `username=patched`, http.StatusUnsupportedMediaType},
+	}
+	for _, test := range tests {
+		req, err := http.NewRequest("PATCH", server.URL+"/users/"+string(u.ID), strings.NewReader(test.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", test.contentType)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != test.status {
+			t.Errorf("%s %s: expected status %d, got %d.", test.contentType, test.body, test.status, resp.StatusCode)
+		}
+	}
+
+	patched := user.FindByID(context.Background(), u.ID)
+	if patched.Username != "patched" || patched.Address.Address != "patched@example.com" {
+		t.Error("User was not patched.")
+	}
+	if !user.CheckPassword(context.Background(), patched, "1@E4s67890") {
+		t.Error("Password should not have been patched.")
+	}
+}
+
+func TestRolePatch(t *testing.T) {
+	addr, _ := mail.ParseAddress("promoted@example.com")
+	u := user.Create(context.Background(), user.User{Username: "promoted", Password: "1@E4s67890", Address: addr})
+	addr, _ = mail.ParseAddress("promoter@example.com")
+	admin := user.Create(context.Background(), user.User{Username: "promoter", Password: "1@E4s67890", Address: addr, Role: user.RoleAdmin})
+	adminToken, err := getAuthToken(user.User{ID: admin.ID, Password: "1@E4s67890"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	userToken, err := getAuthToken(user.User{ID: u.ID, Password: "1@E4s67890"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		token  string
+		body   string
+		status int
+		role   string
+	}{
+		{"", `{"role":"admin"}`, http.StatusUnauthorized, user.RoleUser},
+		{userToken, `{"role":"admin"}`, http.StatusForbidden, user.RoleUser},
+		{adminToken, `{"role":"root"}`, http.StatusBadRequest, user.RoleUser},
+		{adminToken, `{"role":"admin"}`, http.StatusOK, user.RoleAdmin},
+		{adminToken, `{"role":"user"}`, http.StatusOK, user.RoleUser},
+		{adminToken, `{"role":"admin"}`, http.StatusOK, user.RoleAdmin},
+		{"", `{"role":null}`, http.StatusUnauthorized, user.RoleAdmin},
+		{adminToken, `{"role":null}`, http.StatusOK, user.RoleUser},
+	}
+	for _, test := range tests {
+		req, _ := http.NewRequest("PATCH", server.URL+"/v1/users/"+string(u.ID), strings.NewReader(test.body))
+		req.Header.Set("Content-Type", MergePatchType)
+		if test.token != "" {
+			req.Header.Set("Authorization", test.token)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != test.status {
+			t.Errorf("%s: expected status %d, got %d.", test.body, test.status, resp.StatusCode)
+		}
+		if role := user.FindByID(context.Background(), u.ID).Role; role != test.role {
+			t.Errorf("%s: expected role %q, got %q.", test.body, test.role, role)
+		}
+	}
+}
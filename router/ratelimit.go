@@ -0,0 +1,109 @@
+// Package router contains endpoint information for the service.
+//
+// sn - https://github.com/sn
+package router
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/sn/service/config"
+	"github.com/sn/service/logging"
+	"github.com/sn/service/metrics"
+	"github.com/sn/service/ratelimit"
+	"github.com/sn/service/session"
+)
+
+// RateLimits are the rate limits of clients
+var RateLimits = config.Default().Router.RateLimit
+
+// Limiter stores the rate limit buckets of clients
+var Limiter ratelimit.Store = ratelimit.NewMemory()
+
+// clientKey identifies the client of a request by its user ID, if
+// authenticated, or its IP address
+func clientKey(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		if s := session.Find(r.Context(), auth); s.ID != "" {
+			return "user:" + string(s.UserID)
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// routeLimit returns the bucket, cost, and limit of a route
+func routeLimit(route string) (string, int, ratelimit.Limit) {
+	limit := ratelimit.Limit{Requests: RateLimits.Requests, Period: RateLimits.Period}
+	rate := RateLimits.Routes[route]
+	cost := rate.Cost
+	if cost == 0 {
+		cost = 1
+	}
+	if rate.Requests == 0 {
+		return "", cost, limit
+	}
+	limit.Requests = rate.Requests
+	if rate.Period > 0 {
+		limit.Period = rate.Period
+	}
+	return route, cost, limit
+}
+
+// versionPrefix matches the API version prefix of a path
+var versionPrefix = regexp.MustCompile(`^/v[0-9]+(/|$)`)
+
+// unversioned removes the API version prefix from a path template, so each
+// version of a route and its alias share their limits
+func unversioned(template string) string {
+	return versionPrefix.ReplaceAllString(template, "/")
+}
+
+// ceilSeconds rounds a duration up to whole seconds
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// RateLimit limits how often each client can request routes, taking the cost
+// of the route from the client's bucket. Responses carry the RateLimit-Policy,
+// RateLimit-Limit, RateLimit-Remaining, and RateLimit-Reset headers, and a
+// request over the limit is refused with 429 and Retry-After. Requests are
+// let through if the limiter fails.
+func RateLimit(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if RateLimits.Requests <= 0 {
+			h.ServeHTTP(w, r)
+			return
+		}
+		route := r.Method + " " + unversioned(routeTemplate(r))
+		bucket, cost, limit := routeLimit(route)
+		res, err := Limiter.Take(r.Context(), clientKey(r)+" "+bucket, cost, limit)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("rate limit failed", "error", err)
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", limit.Requests, ceilSeconds(limit.Period)))
+		w.Header().Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+		w.Header().Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+		if !res.Allowed {
+			metrics.RateLimited.WithLabelValues(routeTemplate(r)).Inc()
+			w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(res.RetryAfter)))
+			w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+			w.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprint(w, "Too Many Requests")
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,105 @@
+// Package router contains endpoint information for the service.
+//
+// sn - https://github.com/sn
+package router
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/sn/service/config"
+	"github.com/sn/service/helpers"
+	"github.com/sn/service/ratelimit"
+	"github.com/sn/service/session"
+	"github.com/sn/service/user"
+)
+
+// limitRate rate limits requests until the test ends
+func limitRate(t *testing.T, c config.RateLimit, store ratelimit.Store) {
+	prevLimits, prevLimiter := RateLimits, Limiter
+	RateLimits, Limiter = c, store
+	t.Cleanup(func() { RateLimits, Limiter = prevLimits, prevLimiter })
+}
+
+func TestRateLimit(t *testing.T) {
+	limitRate(t, config.RateLimit{
+		Requests: 3,
+		Period:   time.Minute,
+		Routes: map[string]config.RouteRate{
+			"POST /users": {Cost: 2},
+			"POST /auth":  {Requests: 1, Period: time.Hour},
+		},
+	}, ratelimit.NewMemory())
+	router := NewRouter()
+
+	get := func(method, path, auth, addr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.RemoteAddr = addr
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for i := 2; i >= 0; i-- {
+		rec := get("GET", "/v1/users", "", "192.0.2.1:1234")
+		if rec.Code != http.StatusOK || rec.Header().Get("RateLimit-Remaining") != strconv.Itoa(i) {
+			t.Errorf("Expected 200 with %d remaining, got %d %q.", i, rec.Code, rec.Header().Get("RateLimit-Remaining"))
+		}
+		if rec.Header().Get("RateLimit-Limit") != "3" || rec.Header().Get("RateLimit-Policy") != "3;w=60" {
+			t.Error("Expected the limit and policy headers.")
+		}
+	}
+	rec := get("GET", "/users", "", "192.0.2.1:5678")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "20" || rec.Header().Get("RateLimit-Reset") != "60" {
+		t.Errorf("Expected 429 retrying after 20 seconds, got %d %q.", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	if rec := get("GET", "/v1/users", "", "192.0.2.2:1234"); rec.Code != http.StatusOK {
+		t.Error("Expected another address to be limited separately.")
+	}
+
+	u := user.FindByUsername(context.Background(), "blake")
+	token := helpers.GenerateSha1Hash(string(session.Create(context.Background(), u.ID).ID))
+	if rec := get("GET", "/v1/users", token, "192.0.2.1:1234"); rec.Code != http.StatusOK {
+		t.Error("Expected an authenticated user to be limited by user ID.")
+	}
+	if rec := get("POST", "/users", token, "192.0.2.1:1234"); rec.Code == http.StatusTooManyRequests || rec.Header().Get("RateLimit-Remaining") != "0" {
+		t.Errorf("Expected the route to cost two requests, got %d %q.", rec.Code, rec.Header().Get("RateLimit-Remaining"))
+	}
+	if rec := get("POST", "/auth", "", "192.0.2.1:1234"); rec.Code == http.StatusTooManyRequests || rec.Header().Get("RateLimit-Policy") != "1;w=3600" {
+		t.Error("Expected the route to have its own limit.")
+	}
+	if rec := get("POST", "/auth", "", "192.0.2.1:1234"); rec.Code != http.StatusTooManyRequests {
+		t.Error("Expected the route limit to be enforced.")
+	}
+	if rec := get("GET", "/unknown", "", "192.0.2.1:1234"); rec.Code != http.StatusNotFound {
+		t.Error("Expected unmatched requests not to be limited.")
+	}
+	if rec := get("GET", "/healthz", "", "192.0.2.1:1234"); rec.Code != http.StatusOK {
+		t.Error("Expected operational routes not to be limited.")
+	}
+}
+
+// failingStore is a rate limit store that always fails
+type failingStore struct{}
+
+func (failingStore) Take(context.Context, string, int, ratelimit.Limit) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errors.New("store unavailable")
+}
+
+func TestRateLimitStoreError(t *testing.T) {
+	captureLogs(t, "info")
+	limitRate(t, config.RateLimit{Requests: 1, Period: time.Minute}, failingStore{})
+	rec := httptest.NewRecorder()
+	NewRouter().ServeHTTP(rec, httptest.NewRequest("GET", "/v1/users", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("RateLimit-Limit") != "" {
+		t.Errorf("Expected requests to be let through when the store fails, got %d.", rec.Code)
+	}
+}
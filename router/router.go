@@ -3,21 +3,122 @@
 // sn - https://github.com/sn
 package router
 
-import "github.com/gorilla/mux"
+import (
+	"net/http"
+	"strings"
 
-// NewRouter sets up the URL routes
-func NewRouter() *mux.Router {
-	router := mux.NewRouter().StrictSlash(true)
+	"github.com/gorilla/mux"
+	"github.com/sn/service/config"
+	"github.com/sn/service/health"
+	"github.com/sn/service/metrics"
+)
 
-	router.Handle("/", Index).Methods("GET")
+// BodyLimit is the largest request body read, in bytes
+var BodyLimit int64 = 1048576
+
+// Configure applies the router configuration
+func Configure(c config.Router) {
+	BodyLimit = c.BodyLimit
+	BatchConcurrency = c.BatchConcurrency
+	MaxBatchSize = c.MaxBatchSize
+	IdempotencyWindow = c.IdempotencyWindow
+	IdempotencyKeys = c.IdempotencyKeys
+	CrossOrigin = c.CORS
+	RateLimits = c.RateLimit
+	Compression = c.Compression
+}
+
+// middleware wraps every request, including those not matching a route
+var middleware = []mux.MiddlewareFunc{RequestID, Trace, Log, Instrument, Recover, CORS, Compress}
+
+// api wraps the routes of the API, but not the operational routes
+var api = []mux.MiddlewareFunc{Negotiate, RateLimit, ValidateRequest, Idempotent}
+
+// chain wraps a handler in the middleware
+func chain(h http.Handler) http.Handler {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		h = middleware[i](h)
+	}
+	return h
+}
+
+// versions lists the API versions, by path prefix. Each version registers its
+// own routes, so a new version can replace the handlers whose requests or
+// responses change while reusing the rest; all versions share the stores.
+var versions = []struct {
+	prefix string
+	routes func(*mux.Router)
+}{
+	{"/v1", v1},
+}
+
+// aliasVersion is the API version served at the unversioned paths
+const aliasVersion = "/v1"
+
+// v1 registers the routes of version 1 of the API
+func v1(router *mux.Router) {
 	router.Handle("/auth", Auth).Methods("POST")
+	router.Handle("/auth", Logout).Methods("DELETE")
+	router.Handle("/sessions", SessionIndex).Methods("GET")
+	router.Handle("/sessions/{sessionId}", requireUUID("sessionId", SessionDelete)).Methods("DELETE")
+
+	router.Handle("/usernames/reserved", ReservedIndex).Methods("GET")
+	router.Handle("/usernames/reserved/{username}", ReservedAdd).Methods("PUT")
+	router.Handle("/usernames/reserved/{username}", ReservedDelete).Methods("DELETE")
+	router.Handle("/usernames/banned", BannedIndex).Methods("GET")
+	router.Handle("/usernames/banned", BannedAdd).Methods("POST")
+	router.Handle("/usernames/banned", BannedDelete).Methods("DELETE")
 
 	router.Handle("/users", UserIndex).Methods("GET")
 	router.Handle("/users", UserCreate).Methods("POST")
-	router.Handle("/users/{userId}", UserShow).Methods("GET")
-	router.Handle("/users/{userId}", UserUpdate).Methods("PUT")
-	router.Handle("/users/{userId}", UserPatch).Methods("PATCH")
-	router.Handle("/users/{userId}", UserDelete).Methods("DELETE")
+	router.Handle("/users:batch", UserBatch).Methods("POST")
+	router.Handle("/users/{userId}", requireUUID("userId", UserShow)).Methods("GET")
+	router.Handle("/users/{userId}", requireUUID("userId", UserUpdate)).Methods("PUT")
+	router.Handle("/users/{userId}", requireUUID("userId", UserPatch)).Methods("PATCH")
+	router.Handle("/users/{userId}", requireUUID("userId", UserDelete)).Methods("DELETE")
+}
+
+// NewRouter sets up the URL routes. The API is served under /v1, and at its
+// unversioned paths as deprecated aliases.
+func NewRouter() *mux.Router {
+	router := mux.NewRouter().StrictSlash(true)
+	router.Use(middleware...)
+	router.NotFoundHandler = chain(http.NotFoundHandler())
+	router.MethodNotAllowedHandler = chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "OPTIONS" {
+			options(router, w, r)
+			return
+		}
+		methodNotAllowed(router, w, r)
+	}))
+
+	router.Handle("/", Index).Methods("GET")
+	router.Handle("/metrics", metrics.Handler()).Methods("GET")
+	router.Handle("/healthz", health.Live).Methods("GET")
+	router.Handle("/readyz", health.Ready).Methods("GET")
+	router.Handle("/openapi.json", OpenAPI).Methods("GET")
+
+	var aliasRoutes func(*mux.Router)
+	for _, v := range versions {
+		sub := router.PathPrefix(v.prefix).Subrouter()
+		sub.Use(api...)
+		v.routes(sub)
+		if v.prefix == aliasVersion {
+			aliasRoutes = v.routes
+		}
+	}
+	// The aliases match any path, so they are registered after every version
+	aliases := router.NewRoute().Subrouter()
+	aliases.Use(Deprecated)
+	aliases.Use(api...)
+	aliasRoutes(aliases)
 
 	return router
 }
+
+// methodNotAllowed responds with 405 to requests for a route with another
+// method, listing the methods allowed
+func methodNotAllowed(router *mux.Router, w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Allow", strings.Join(append(allowedMethods(router, r), "OPTIONS"), ", "))
+	w.WriteHeader(http.StatusMethodNotAllowed)
+}
@@ -0,0 +1,68 @@
+// Package router contains endpoint information for the service.
+//
+// sn - https://github.com/sn
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestVersions(t *testing.T) {
+	router := NewRouter()
+
+	tests := []struct {
+		path       string
+		status     int
+		deprecated bool
+	}{
+		{"/v1/users", http.StatusOK, false},
+		{"/users", http.StatusOK, true},
+		{"/v1/users/garbage", http.StatusBadRequest, false},
+		{"/users/garbage", http.StatusBadRequest, true},
+		{"/healthz", http.StatusOK, false},
+		{"/v1/healthz", http.StatusNotFound, false},
+		{"/v2/users", http.StatusNotFound, false},
+	}
+	for _, test := range tests {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest("GET", test.path, nil))
+		if rec.Code != test.status {
+			t.Errorf("%s: expected status %d, got %d.", test.path, test.status, rec.Code)
+		}
+		if (rec.Header().Get("Deprecation") != "") != test.deprecated {
+			t.Errorf("%s: expected deprecated to be %v.", test.path, test.deprecated)
+		}
+		if !test.deprecated {
+			continue
+		}
+		if rec.Header().Get("Deprecation") != "@1792368000" || rec.Header().Get("Sunset") != "Mon, 19 Apr 2027 00:00:00 GMT" {
+			t.Errorf("%s: expected deprecation and sunset dates, got %q and %q.", test.path, rec.Header().Get("Deprecation"), rec.Header().Get("Sunset"))
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("PUT", "/v1/users", nil))
+	if rec.Code != http.StatusMethodNotAllowed || rec.Header().Get("Allow") != "GET, POST, OPTIONS" {
+		t.Errorf("Expected 405 with Allow header, got %d %q.", rec.Code, rec.Header().Get("Allow"))
+	}
+}
+
+func TestAliasVersion(t *testing.T) {
+	defer func(v1 func(*mux.Router)) { versions[0].routes = v1 }(versions[0].routes)
+	versions[0].routes = func(router *mux.Router) {
+		router.Handle("/ping", Index).Methods("GET")
+	}
+	router := NewRouter()
+
+	for path, status := range map[string]int{"/v1/ping": http.StatusOK, "/ping": http.StatusOK, "/users": http.StatusNotFound} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		if rec.Code != status {
+			t.Errorf("%s: expected status %d, got %d.", path, status, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+// Package router contains endpoint information for the service.
+//
+// sn - https://github.com/sn
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sn/service/session"
+	"github.com/sn/service/types"
+	"github.com/sn/service/user"
+)
+
+// authenticate returns the session of the Authorization header of a request
+// and its user, or a *statusError with 401 if the session has expired or the
+// user is deactivated
+func authenticate(r *http.Request) (session.Session, user.User, error) {
+	s := session.Find(r.Context(), r.Header.Get("Authorization"))
+	if s.ID == "" || !time.Now().Before(s.Expires) {
+		return s, user.User{}, &statusError{http.StatusUnauthorized, "Unauthorized"}
+	}
+	u := user.FindByID(r.Context(), s.UserID)
+	if len(u.ID) == 0 || u.Deactivated {
+		return s, u, &statusError{http.StatusUnauthorized, "Unauthorized"}
+	}
+	return s, u, nil
+}
+
+// authorizeAdmin authenticates a request like authenticate, and returns a
+// *statusError with 403 if its user is not an admin
+func authorizeAdmin(r *http.Request) (user.User, error) {
+	_, u, err := authenticate(r)
+	if err != nil {
+		return u, err
+	}
+	if u.Role != user.RoleAdmin {
+		return u, &statusError{http.StatusForbidden, "Forbidden"}
+	}
+	return u, nil
+}
+
+// Logout handles DELETE /auth, ending the session of the request
+var Logout = handler(func(w http.ResponseWriter, r *http.Request) error {
+	s, _, err := authenticate(r)
+	if err != nil {
+		return err
+	}
+	if err := session.Remove(s.ID); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+})
+
+// sessionResponse is a session as listed. Its ID is the public ID of the
+// session, since the session token can be derived from the session ID.
+type sessionResponse struct {
+	ID      types.UUID
+	UserID  types.UUID
+	Expires time.Time
+}
+
+// SessionIndex handles GET /sessions
+//
+// Users see their own sessions, and admins every session, or a user's with
+// the user_id query parameter. Expired sessions are not listed.
+var SessionIndex = handler(func(w http.ResponseWriter, r *http.Request) error {
+	_, u, err := authenticate(r)
+	if err != nil {
+		return err
+	}
+	userID := u.ID
+	if u.Role == user.RoleAdmin {
+		userID = ""
+		if param := r.URL.Query().Get("user_id"); param != "" {
+			if userID, err = types.Parse(param); err != nil {
+				return &statusError{http.StatusBadRequest, "Invalid user_id."}
+			}
+		}
+	}
+	now := time.Now()
+	sessions := []sessionResponse{}
+	for _, s := range session.GetAll() {
+		if now.Before(s.Expires) && (userID == "" || s.UserID == userID) {
+			sessions = append(sessions, sessionResponse{ID: s.PublicID, UserID: s.UserID, Expires: s.Expires})
+		}
+	}
+	return respond(w, r, http.StatusOK, sessions)
+})
+
+// SessionDelete handles DELETE /sessions/:sessionId, by public ID
+//
+// Users can revoke their own sessions, and admins any session.
+var SessionDelete = handler(func(w http.ResponseWriter, r *http.Request) error {
+	_, u, err := authenticate(r)
+	if err != nil {
+		return err
+	}
+	id, _ := types.Parse(mux.Vars(r)["sessionId"])
+	s := session.GetPublic(id)
+	if s.ID == "" || (s.UserID != u.ID && u.Role != user.RoleAdmin) {
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, "Not found")
+		return nil
+	}
+	if err := session.Remove(s.ID); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+})
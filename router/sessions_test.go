@@ -0,0 +1,125 @@
+// Package router contains endpoint information for the service.
+//
+// sn - https://github.com/sn
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sn/service/helpers"
+	"github.com/sn/service/session"
+	"github.com/sn/service/user"
+)
+
+// listSessions lists the sessions visible to a token
+func listSessions(t *testing.T, token, query string) ([]sessionResponse, int) {
+	req, _ := http.NewRequest("GET", server.URL+"/v1/sessions"+query, nil)
+	req.Header.Set("Authorization", token)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var sessions []sessionResponse
+	if resp.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(resp.Body).Decode(&sessions); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return sessions, resp.StatusCode
+}
+
+// deleteSession makes a DELETE request with a token
+func deleteSession(t *testing.T, token, path string) int {
+	req, _ := http.NewRequest("DELETE", server.URL+path, nil)
+	req.Header.Set("Authorization", token)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestSessions(t *testing.T) {
+	corey := user.FindByUsername(context.Background(), "corey")
+	token, err := getAuthToken(user.User{ID: corey.ID, Password: "1@E4s67890"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := getAuthToken(user.User{ID: corey.ID, Password: "1@E4s67890"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr, _ := mail.ParseAddress("quinn@example.com")
+	admin := user.Create(context.Background(), user.User{Username: "quinn", Password: "1@E4s67890", Address: addr, Role: user.RoleAdmin, Created: time.Now()})
+	adminToken, err := getAuthToken(user.User{ID: admin.ID, Password: "1@E4s67890"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, status := listSessions(t, "garbage", ""); status != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without a session, got %d.", status)
+	}
+	sessions, status := listSessions(t, token, "?user_id="+string(admin.ID))
+	if status != http.StatusOK || len(sessions) < 2 {
+		t.Fatalf("Expected the user's sessions, got %d %v.", status, sessions)
+	}
+	for _, s := range sessions {
+		if s.UserID != corey.ID {
+			t.Errorf("Expected only the user's sessions, got %v.", s)
+		}
+	}
+	if sessions, _ := listSessions(t, adminToken, "?user_id="+strings.ToUpper(string(corey.ID))); len(sessions) < 2 || sessions[0].UserID != corey.ID {
+		t.Errorf("Expected admins to list the user's sessions, got %v.", sessions)
+	}
+	if _, status := listSessions(t, adminToken, "?user_id=garbage"); status != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid user ID, got %d.", status)
+	}
+
+	theirs := session.Find(context.Background(), other)
+	adminSession := session.Find(context.Background(), adminToken)
+	if status := deleteSession(t, token, "/v1/sessions/"+string(adminSession.PublicID)); status != http.StatusNotFound {
+		t.Errorf("Expected 404 revoking another user's session, got %d.", status)
+	}
+	if status := deleteSession(t, adminToken, "/v1/sessions/"+strings.ToUpper(string(theirs.PublicID))); status != http.StatusNoContent {
+		t.Errorf("Expected admins to revoke the session, got %d.", status)
+	}
+	if _, status := listSessions(t, other, ""); status != http.StatusUnauthorized {
+		t.Errorf("Expected the revoked session to be unauthorized, got %d.", status)
+	}
+
+	if status := deleteSession(t, token, "/v1/auth"); status != http.StatusNoContent {
+		t.Errorf("Expected 204 logging out, got %d.", status)
+	}
+	if status := deleteSession(t, token, "/v1/auth"); status != http.StatusUnauthorized {
+		t.Errorf("Expected 401 after logging out, got %d.", status)
+	}
+}
+
+func TestSessionIDsAreNotTokens(t *testing.T) {
+	addr, _ := mail.ParseAddress("rowan@example.com")
+	rowan := user.Create(context.Background(), user.User{Username: "rowan", Password: "1@E4s67890", Address: addr, Created: time.Now()})
+	token, err := getAuthToken(user.User{ID: rowan.ID, Password: "1@E4s67890"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessions, status := listSessions(t, token, "")
+	if status != http.StatusOK || len(sessions) == 0 {
+		t.Fatalf("Expected the user's sessions, got %d %v.", status, sessions)
+	}
+	for _, s := range sessions {
+		if session.Get(s.ID).ID != "" {
+			t.Errorf("Expected %s not to be a session ID.", s.ID)
+		}
+		if _, status := listSessions(t, helpers.GenerateSha1Hash(string(s.ID)), ""); status != http.StatusUnauthorized {
+			t.Errorf("Expected a token derived from a listed ID to be unauthorized, got %d.", status)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+// Package router contains endpoint information for the service.
+//
+// sn - https://github.com/sn
+package router
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/sn/service/user"
+)
+
+// ReservedIndex handles GET /usernames/reserved
+var ReservedIndex = handler(func(w http.ResponseWriter, r *http.Request) error {
+	if _, err := authorizeAdmin(r); err != nil {
+		return err
+	}
+	return respond(w, r, http.StatusOK, user.Reserved())
+})
+
+// ReservedAdd handles PUT /usernames/reserved/:username
+var ReservedAdd = handler(func(w http.ResponseWriter, r *http.Request) error {
+	if _, err := authorizeAdmin(r); err != nil {
+		return err
+	}
+	user.Reserve(mux.Vars(r)["username"])
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+})
+
+// ReservedDelete handles DELETE /usernames/reserved/:username
+var ReservedDelete = handler(func(w http.ResponseWriter, r *http.Request) error {
+	if _, err := authorizeAdmin(r); err != nil {
+		return err
+	}
+	user.Unreserve(mux.Vars(r)["username"])
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+})
+
+// BannedIndex handles GET /usernames/banned
+var BannedIndex = handler(func(w http.ResponseWriter, r *http.Request) error {
+	if _, err := authorizeAdmin(r); err != nil {
+		return err
+	}
+	return respond(w, r, http.StatusOK, user.Banned())
+})
+
+// BannedAdd handles POST /usernames/banned, banning the regular expression
+// of the pattern field of the body
+var BannedAdd = handler(func(w http.ResponseWriter, r *http.Request) error {
+	if _, err := authorizeAdmin(r); err != nil {
+		return err
+	}
+	c, err := requestCodec(r)
+	if err != nil {
+		return err
+	}
+	body, err := readBody(r)
+	if err != nil {
+		return err
+	}
+	var input struct {
+		Pattern string `json:"pattern"`
+	}
+	if err := c.Unmarshal(body, &input); err != nil || input.Pattern == "" {
+		return &statusError{http.StatusBadRequest, "Pattern is required."}
+	}
+	if err := user.Ban(input.Pattern); err != nil {
+		return &statusError{http.StatusBadRequest, "Pattern is not a valid regular expression."}
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+})
+
+// BannedDelete handles DELETE /usernames/banned?pattern=
+var BannedDelete = handler(func(w http.ResponseWriter, r *http.Request) error {
+	if _, err := authorizeAdmin(r); err != nil {
+		return err
+	}
+	user.Unban(r.URL.Query().Get("pattern"))
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+})
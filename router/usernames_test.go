@@ -0,0 +1,81 @@
+// Package router contains endpoint information for the service.
+//
+// sn - https://github.com/sn
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/sn/service/user"
+)
+
+// usernamesRequest makes a request with a token, returning the status and
+// the decoded list, if any
+func usernamesRequest(t *testing.T, method, path, token, body string) (int, []string) {
+	req, _ := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+	req.Header.Set("Authorization", token)
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var list []string
+	if resp.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return resp.StatusCode, list
+}
+
+func TestUsernames(t *testing.T) {
+	blake := user.FindByUsername(context.Background(), "blake")
+	userToken, err := getAuthToken(user.User{ID: blake.ID, Password: "1@E4s67890"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	adminToken := newAdmin(t, "reserver")
+
+	for _, path := range []string{"/v1/usernames/reserved", "/v1/usernames/banned"} {
+		if status, _ := usernamesRequest(t, "GET", path, "garbage", ""); status != http.StatusUnauthorized {
+			t.Errorf("%s: expected 401 without a session, got %d.", path, status)
+		}
+		if status, _ := usernamesRequest(t, "GET", path, userToken, ""); status != http.StatusForbidden {
+			t.Errorf("%s: expected 403 for users, got %d.", path, status)
+		}
+	}
+	if status, _ := usernamesRequest(t, "PUT", "/v1/usernames/reserved/mallory", userToken, ""); status != http.StatusForbidden {
+		t.Errorf("Expected users not to reserve usernames, got %d.", status)
+	}
+
+	if status, _ := usernamesRequest(t, "PUT", "/v1/usernames/reserved/Mallory", adminToken, ""); status != http.StatusNoContent || !user.IsReserved("mallory") {
+		t.Errorf("Expected the username to be reserved, got %d.", status)
+	}
+	if status, list := usernamesRequest(t, "GET", "/v1/usernames/reserved", adminToken, ""); status != http.StatusOK || !strings.Contains(strings.Join(list, " "), "Mallory") {
+		t.Errorf("Expected the reserved username to be listed, got %d %v.", status, list)
+	}
+	if status, _ := usernamesRequest(t, "DELETE", "/v1/usernames/reserved/mallory", adminToken, ""); status != http.StatusNoContent || user.IsReserved("mallory") {
+		t.Errorf("Expected the username to be unreserved, got %d.", status)
+	}
+
+	if status, _ := usernamesRequest(t, "POST", "/v1/usernames/banned", adminToken, `{"pattern":"("}`); status != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid pattern, got %d.", status)
+	}
+	if status, _ := usernamesRequest(t, "POST", "/v1/usernames/banned", adminToken, `{"pattern":"^Troll"}`); status != http.StatusNoContent || !user.IsReserved("trollface") {
+		t.Errorf("Expected the pattern to be banned, got %d.", status)
+	}
+	if status, list := usernamesRequest(t, "GET", "/v1/usernames/banned", adminToken, ""); status != http.StatusOK || len(list) != 1 || list[0] != "^Troll" {
+		t.Errorf("Expected the banned pattern to be listed, got %d %v.", status, list)
+	}
+	if status, _ := usernamesRequest(t, "DELETE", "/v1/usernames/banned?pattern="+url.QueryEscape("^Troll"), adminToken, ""); status != http.StatusNoContent || user.IsReserved("trollface") {
+		t.Errorf("Expected the pattern to be unbanned, got %d.", status)
+	}
+}
@@ -4,15 +4,189 @@
 package main
 
 import (
+	"context"
+	"io"
 	"log"
+	"log/slog"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
-	"github.com/gorilla/handlers"
+	"github.com/sn/service/certs"
+	"github.com/sn/service/config"
+	"github.com/sn/service/health"
+	"github.com/sn/service/logging"
 	"github.com/sn/service/router"
+	"github.com/sn/service/session"
+	"github.com/sn/service/tracing"
+	"github.com/sn/service/user"
 )
 
 func main() {
-	router := router.NewRouter()
-	log.Fatal(http.ListenAndServe(":8080", handlers.LoggingHandler(os.Stdout, router)))
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
+		log.Fatal(err)
+	}
+	session.Configure(cfg.Session)
+	router.Configure(cfg.Router)
+	if err := user.Configure(cfg.User); err != nil {
+		log.Fatal(err)
+	}
+	if _, err := user.CreateAdmin(context.Background(), cfg.User.Admin); err != nil {
+		log.Fatal(err)
+	}
+
+	var out io.Writer = os.Stdout
+	if cfg.Server.LogFile != "" {
+		f, err := os.OpenFile(cfg.Server.LogFile, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		out = f
+	}
+	logger, err := logging.New(out, cfg.Server.LogLevel, cfg.Server.LogFormat)
+	if err != nil {
+		log.Fatal(err)
+	}
+	slog.SetDefault(logger)
+	stopTracing, err := tracing.Setup(cfg.Tracing, out)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	srv := newServer(cfg.Server, router.NewRouter())
+	ln, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var hooks []func(context.Context) error
+	if cfg.Server.TLS.Enabled() {
+		if hooks, err = setupTLS(cfg.Server, srv); err != nil {
+			log.Fatal(err)
+		}
+	}
+	health.Timeout = cfg.Server.ReadyTimeout
+	health.Register("users", user.Ping)
+	health.Register("sessions", session.Ping)
+	janitor := session.StartJanitor(cfg.Session.CleanInterval)
+	hooks = append(hooks, janitor.Stop, stopTracing)
+
+	slog.Info("Listening", "addr", ln.Addr().String(), "tls", srv.TLSConfig != nil)
+	if err := serve(ctx, srv, ln, cfg.Server.DrainDelay, cfg.Server.ShutdownTimeout, hooks...); err != nil {
+		log.Fatal(err)
+	}
+	slog.Info("Shut down")
+}
+
+// newServer creates an HTTP server from the server configuration
+func newServer(c config.Server, h http.Handler) *http.Server {
+	return &http.Server{
+		Addr:         c.Addr,
+		Handler:      h,
+		ReadTimeout:  c.ReadTimeout,
+		WriteTimeout: c.WriteTimeout,
+		IdleTimeout:  c.IdleTimeout,
+	}
+}
+
+// setupTLS configures the server for TLS, reloading the certificate when its
+// files change or on SIGHUP, and starts the HTTP to HTTPS redirect listener if
+// configured. It returns the hooks stopping them.
+func setupTLS(c config.Server, srv *http.Server) ([]func(context.Context) error, error) {
+	reloader, err := certs.NewReloader(c.TLS.CertFile, c.TLS.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	if srv.TLSConfig, err = certs.NewConfig(c.TLS, reloader); err != nil {
+		return nil, err
+	}
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	reloader.Watch(c.TLS.ReloadInterval, hup)
+	hooks := []func(context.Context) error{reloader.Stop}
+
+	if c.TLS.RedirectAddr != "" {
+		rc := c
+		rc.Addr = c.TLS.RedirectAddr
+		redirect := newServer(rc, redirectHTTPS(c.Addr))
+		ln, err := net.Listen("tcp", redirect.Addr)
+		if err != nil {
+			return nil, err
+		}
+		slog.Info("Redirecting to HTTPS", "addr", ln.Addr().String())
+		go func() {
+			if err := redirect.Serve(ln); err != http.ErrServerClosed {
+				slog.Error("Redirect listener failed", "error", err)
+			}
+		}()
+		hooks = append(hooks, redirect.Shutdown)
+	}
+	return hooks, nil
+}
+
+// redirectHTTPS redirects requests to the same URL over HTTPS, on the port of
+// the HTTPS address
+func redirectHTTPS(addr string) http.Handler {
+	_, port, _ := net.SplitHostPort(addr)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := (&url.URL{Host: r.Host}).Hostname()
+		if port != "" && port != "443" {
+			host = net.JoinHostPort(host, port)
+		} else if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+		u := url.URL{Scheme: "https", Host: host, Path: r.URL.Path, RawQuery: r.URL.RawQuery}
+		http.Redirect(w, r, u.String(), http.StatusPermanentRedirect)
+	})
+}
+
+// serve serves HTTP, or HTTPS when the server has a TLS configuration, on the
+// listener until the context is done, then shuts down gracefully: it fails
+// readiness for the drain delay so that no new requests are routed to it,
+// stops accepting connections, waits for in-flight requests to complete, and
+// then runs the shutdown hooks in order, such as stopping background workers
+// and closing stores. Shutting down is abandoned after the timeout.
+func serve(ctx context.Context, srv *http.Server, ln net.Listener, drain, timeout time.Duration, hooks ...func(context.Context) error) error {
+	errc := make(chan error, 1)
+	go func() {
+		if srv.TLSConfig != nil {
+			errc <- srv.ServeTLS(ln, "", "")
+			return
+		}
+		errc <- srv.Serve(ln)
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	health.Drain()
+	if drain > 0 {
+		slog.Info("Draining", "delay", drain)
+		time.Sleep(drain)
+	}
+	slog.Info("Shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	err := srv.Shutdown(shutdownCtx)
+	for _, hook := range hooks {
+		if herr := hook(shutdownCtx); herr != nil && err == nil {
+			err = herr
+		}
+	}
+	if serr := <-errc; serr != http.ErrServerClosed && err == nil {
+		err = serr
+	}
+	return err
 }
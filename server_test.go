@@ -0,0 +1,163 @@
+// Package server is the main program that initiates the server.
+//
+// sn - https://github.com/sn
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sn/service/config"
+	"github.com/sn/service/health"
+)
+
+func TestServeDrainsRequests(t *testing.T) {
+	started := make(chan struct{})
+	var completed int32
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(200 * time.Millisecond)
+		w.Write([]byte("done"))
+		atomic.StoreInt32(&completed, 1)
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := newServer(config.Default().Server, handler)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// The hook records whether requests had drained when it ran
+	drained := make(chan bool, 1)
+	hook := func(ctx context.Context) error {
+		drained <- atomic.LoadInt32(&completed) == 1
+		return nil
+	}
+	served := make(chan error, 1)
+	go func() {
+		served <- serve(ctx, srv, ln, 0, 5*time.Second, hook)
+	}()
+
+	type result struct {
+		body string
+		err  error
+	}
+	results := make(chan result, 1)
+	go func() {
+		resp, err := http.Get("http://" + ln.Addr().String() + "/")
+		if err != nil {
+			results <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		results <- result{string(body), err}
+	}()
+
+	<-started
+	cancel()
+
+	res := <-results
+	if res.err != nil {
+		t.Fatal(res.err)
+	}
+	if res.body != "done" {
+		t.Errorf("Expected in-flight request to complete, got %q.", res.body)
+	}
+	if err := <-served; err != nil {
+		t.Error(err)
+	}
+	select {
+	case ok := <-drained:
+		if !ok {
+			t.Error("Expected shutdown hook to run after requests drained.")
+		}
+	default:
+		t.Error("Expected shutdown hook to run.")
+	}
+	if _, err := http.Get("http://" + ln.Addr().String() + "/"); err == nil {
+		t.Error("Expected server to stop accepting connections.")
+	}
+}
+
+func TestServeShutdownTimeout(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(time.Second)
+	})
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := newServer(config.Default().Server, handler)
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- serve(ctx, srv, ln, 0, 10*time.Millisecond)
+	}()
+
+	go http.Get("http://" + ln.Addr().String() + "/")
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	if err := <-served; err != context.DeadlineExceeded {
+		t.Errorf("Expected shutdown to time out, got %v.", err)
+	}
+}
+
+func TestServeDrainDelay(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := newServer(config.Default().Server, health.Ready)
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- serve(ctx, srv, ln, 300*time.Millisecond, 5*time.Second)
+	}()
+
+	ready := func() int {
+		resp, err := http.Get("http://" + ln.Addr().String() + "/readyz")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	cancel()
+	time.Sleep(50 * time.Millisecond)
+	if status := ready(); status != http.StatusServiceUnavailable {
+		t.Errorf("Expected not to be ready while draining, got %d.", status)
+	}
+	if err := <-served; err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRedirectHTTPS(t *testing.T) {
+	tests := []struct {
+		addr     string
+		url      string
+		location string
+	}{
+		{":443", "http://example.com/users?limit=5", "https://example.com/users?limit=5"},
+		{":8443", "http://example.com:8080/", "https://example.com:8443/"},
+		{":443", "http://[::1]:8080/auth", "https://[::1]/auth"},
+	}
+	for _, test := range tests {
+		rec := httptest.NewRecorder()
+		redirectHTTPS(test.addr).ServeHTTP(rec, httptest.NewRequest("POST", test.url, nil))
+		if rec.Code != http.StatusPermanentRedirect {
+			t.Errorf("%s: expected status 308, got %d.", test.url, rec.Code)
+		}
+		if location := rec.Header().Get("Location"); location != test.location {
+			t.Errorf("%s: expected redirect to %s, got %s.", test.url, test.location, location)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+// Package session manages the sessions for the application.
+//
+// sn - https://github.com/sn
+package session
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Janitor periodically removes expired sessions in the background
+type Janitor struct {
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// StartJanitor starts a janitor that cleans sessions every interval
+func StartJanitor(interval time.Duration) *Janitor {
+	j := &Janitor{stop: make(chan struct{}), done: make(chan struct{})}
+	go func() {
+		defer close(j.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				Clean()
+			case <-j.stop:
+				return
+			}
+		}
+	}()
+	return j
+}
+
+// Stop stops the janitor, waiting for a clean in progress to finish or the
+// context to be done. Stop can be called more than once.
+func (j *Janitor) Stop(ctx context.Context) error {
+	j.stopOnce.Do(func() { close(j.stop) })
+	select {
+	case <-j.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,32 @@
+// Package session manages the sessions for the application.
+//
+// sn - https://github.com/sn
+package session
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sn/service/helpers"
+)
+
+func TestJanitor(t *testing.T) {
+	s := Create(context.Background(), helpers.GenerateUUID())
+	Expire(context.Background(), s.ID)
+
+	j := StartJanitor(time.Millisecond)
+	deadline := time.Now().Add(time.Second)
+	for len(Get(s.ID).ID) > 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if err := j.Stop(context.Background()); err != nil {
+		t.Error(err)
+	}
+	if len(Get(s.ID).ID) > 0 {
+		t.Error("Janitor did not remove expired session.")
+	}
+	if err := j.Stop(context.Background()); err != nil {
+		t.Errorf("Expected stopping again to succeed, got %v.", err)
+	}
+}
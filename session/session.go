@@ -4,34 +4,65 @@
 package session
 
 import (
+	"context"
 	"fmt"
+	"sync"
 	"time"
 
+	"github.com/sn/service/config"
+	"github.com/sn/service/health"
 	"github.com/sn/service/helpers"
+	"github.com/sn/service/metrics"
+	"github.com/sn/service/tracing"
 	"github.com/sn/service/types"
 )
 
-// Session contains a user's session
+// Session contains a user's session. The session token is derived from the
+// ID, so it is kept secret; the public ID identifies the session to users.
 type Session struct {
-	ID      types.UUID
-	UserID  types.UUID
-	Expires time.Time
+	ID       types.UUID
+	PublicID types.UUID
+	UserID   types.UUID
+	Expires  time.Time
 }
 
-// Expiration represents how much time a session lasts (one day)
-const Expiration = 86400
+// Expiration represents how much time a session lasts (one day by default)
+var Expiration = 24 * time.Hour
 
-var sessions []Session
+var (
+	mu       sync.RWMutex
+	sessions []Session
+)
+
+func init() {
+	metrics.GaugeFunc("sessions_active", "Sessions that have not expired.", func() float64 { return float64(Active()) })
+}
+
+// Configure applies the session configuration
+func Configure(c config.Session) {
+	Expiration = c.Lifetime
+}
 
 // Create creates a new session
-func Create(userID types.UUID) Session {
-	s := Session{ID: helpers.GenerateUUID(), UserID: userID, Expires: time.Now().AddDate(0, 0, 1)}
+func Create(ctx context.Context, userID types.UUID) Session {
+	_, span := tracing.Start(ctx, "session.Create")
+	defer span.End()
+	s := Session{ID: helpers.GenerateUUID(), PublicID: helpers.GenerateUUID(), UserID: userID, Expires: time.Now().Add(Expiration)}
+	mu.Lock()
+	defer mu.Unlock()
 	sessions = append(sessions, s)
 	return s
 }
 
+// Ping checks that the store can be read, for readiness checks
+func Ping(ctx context.Context) error {
+	return health.ProbeLock(ctx, &mu, "Session store")
+}
+
 // Get retrieves a session given a user ID
 func Get(id types.UUID) Session {
+	mu.RLock()
+	defer mu.RUnlock()
 	for _, s := range sessions {
 		if s.ID == id {
 			return s
@@ -40,13 +71,45 @@ func Get(id types.UUID) Session {
 	return Session{}
 }
 
-// GetAll retrieves all sessions
+// GetPublic retrieves a session given its public ID
+func GetPublic(id types.UUID) Session {
+	mu.RLock()
+	defer mu.RUnlock()
+	for _, s := range sessions {
+		if s.PublicID == id {
+			return s
+		}
+	}
+	return Session{}
+}
+
+// GetAll retrieves a copy of all sessions
 func GetAll() []Session {
-	return sessions
+	mu.RLock()
+	defer mu.RUnlock()
+	return append([]Session(nil), sessions...)
+}
+
+// Active returns the number of sessions that have not expired
+func Active() int {
+	mu.RLock()
+	defer mu.RUnlock()
+	now := time.Now()
+	n := 0
+	for _, s := range sessions {
+		if now.Before(s.Expires) {
+			n++
+		}
+	}
+	return n
 }
 
 // Expire sets the expiration of a session well into the past
-func Expire(id types.UUID) error {
+func Expire(ctx context.Context, id types.UUID) error {
+	_, span := tracing.Start(ctx, "session.Expire")
+	defer span.End()
+	mu.Lock()
+	defer mu.Unlock()
 	for i, s := range sessions {
 		if s.ID == id {
 			sessions[i].Expires = time.Time{}
@@ -57,7 +120,11 @@ func Expire(id types.UUID) error {
 }
 
 // Find retrieves a session given a session hash
-func Find(hash string) Session {
+func Find(ctx context.Context, hash string) Session {
+	_, span := tracing.Start(ctx, "session.Find")
+	defer span.End()
+	mu.RLock()
+	defer mu.RUnlock()
 	for _, s := range sessions {
 		if helpers.GenerateSha1Hash(string(s.ID)) == hash {
 			return s
@@ -67,10 +134,14 @@ func Find(hash string) Session {
 }
 
 // Bump bumps the expiration time up for a given session UUID
-func Bump(id types.UUID) error {
+func Bump(ctx context.Context, id types.UUID) error {
+	_, span := tracing.Start(ctx, "session.Bump")
+	defer span.End()
+	mu.Lock()
+	defer mu.Unlock()
 	for i, s := range sessions {
 		if s.ID == id {
-			sessions[i].Expires = time.Now().AddDate(0, 0, 1)
+			sessions[i].Expires = time.Now().Add(Expiration)
 			return nil
 		}
 	}
@@ -79,9 +150,11 @@ func Bump(id types.UUID) error {
 
 // Clean removes any expired sessions
 func Clean() {
+	mu.Lock()
+	defer mu.Unlock()
 	for i := 0; i < len(sessions); i++ {
 		if time.Now().After(sessions[i].Expires) {
-			Remove(sessions[i].ID)
+			remove(sessions[i].ID)
 			i--
 		}
 	}
@@ -89,6 +162,12 @@ func Clean() {
 
 // Remove removes a session from the existing sessions
 func Remove(id types.UUID) error {
+	mu.Lock()
+	defer mu.Unlock()
+	return remove(id)
+}
+
+func remove(id types.UUID) error {
 	for i, s := range sessions {
 		if s.ID == id {
 			sessions = append(sessions[:i], sessions[i+1:]...)
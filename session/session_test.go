@@ -4,15 +4,17 @@
 package session
 
 import (
+	"context"
 	"log"
 	"net/mail"
 	"os"
-	"strings"
 	"testing"
 	"time"
 
 	"github.com/sn/service/helpers"
 	"github.com/sn/service/user"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
 )
 
 func TestCreate(t *testing.T) {
@@ -20,7 +22,7 @@ func TestCreate(t *testing.T) {
 	if userID == "" {
 		t.Error("Could not generate UUID")
 	}
-	newSession := Create(userID)
+	newSession := Create(context.Background(), userID)
 	if newSession.UserID != userID {
 		t.Error("User UUID mismatch.")
 	}
@@ -46,6 +48,20 @@ func TestGet(t *testing.T) {
 	}
 }
 
+func TestPing(t *testing.T) {
+	if err := Ping(context.Background()); err != nil {
+		t.Error(err)
+	}
+	mu.Lock()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := Ping(ctx)
+	mu.Unlock()
+	if err == nil {
+		t.Error("Expected a locked store to be unavailable.")
+	}
+}
+
 func TestGetAll(t *testing.T) {
 	sessions := GetAll()
 	if len(sessions) == 0 {
@@ -53,17 +69,29 @@ func TestGetAll(t *testing.T) {
 	}
 }
 
+func TestActive(t *testing.T) {
+	before := Active()
+	s := Create(context.Background(), helpers.GenerateUUID())
+	if Active() != before+1 {
+		t.Error("Expected a new session to be active.")
+	}
+	Expire(context.Background(), s.ID)
+	if Active() != before {
+		t.Error("Expected an expired session not to be active.")
+	}
+}
+
 func TestExpire(t *testing.T) {
 	s := Session{}
-	err := Expire(s.ID)
+	err := Expire(context.Background(), s.ID)
 	if err.Error() != "Could not find session" {
 		t.Error("Remove fail should specify not found.")
 	}
 	sessions := GetAll()
 	for _, s := range sessions {
-		Expire(s.ID)
+		Expire(context.Background(), s.ID)
 	}
-	for _, s := range sessions {
+	for _, s := range GetAll() {
 		if !s.Expires.IsZero() {
 			t.Error("Incorrect session expiration.")
 		}
@@ -72,13 +100,13 @@ func TestExpire(t *testing.T) {
 
 func TestFind(t *testing.T) {
 	s := Session{}
-	s = Find("")
+	s = Find(context.Background(), "")
 	if len(s.ID) != 0 {
 		t.Error("Find fail should return empty session.")
 	}
 	sessions := GetAll()
 	sessionHash := helpers.GenerateSha1Hash(string(sessions[0].ID))
-	s = Find(sessionHash)
+	s = Find(context.Background(), sessionHash)
 	if s.Expires.Sub(sessions[0].Expires) != 0 {
 		t.Error("Incorrect session was obtained.")
 	}
@@ -90,13 +118,13 @@ func TestFind(t *testing.T) {
 
 func TestBump(t *testing.T) {
 	s := Session{}
-	err := Bump(s.ID)
+	err := Bump(context.Background(), s.ID)
 	if err.Error() != "Could not find session" {
 		t.Error("Bump fail should specify not found.")
 	}
 	sessions := GetAll()
 	s = Get(sessions[0].ID)
-	err = Bump(sessions[0].ID)
+	err = Bump(context.Background(), sessions[0].ID)
 	if err != nil {
 		t.Error(err)
 	}
@@ -108,7 +136,7 @@ func TestBump(t *testing.T) {
 func TestClean(t *testing.T) {
 	sessions := GetAll()
 	for _, s := range sessions {
-		Expire(s.ID)
+		Expire(context.Background(), s.ID)
 	}
 	Clean()
 	sessions = GetAll()
@@ -123,7 +151,7 @@ func TestRemove(t *testing.T) {
 	if err.Error() != "Could not find session" {
 		t.Error("Remove fail should specify not found.")
 	}
-	s = Create(helpers.GenerateUUID())
+	s = Create(context.Background(), helpers.GenerateUUID())
 	sessions := GetAll()
 	err = Remove(s.ID)
 	if len(sessions) == len(GetAll()) {
@@ -131,16 +159,28 @@ func TestRemove(t *testing.T) {
 	}
 }
 
+func TestGetAllCopies(t *testing.T) {
+	s := Create(context.Background(), "ab2e4c1d-0c4d-4f8b-877d-5dbe1e6c0d3d")
+	sessions := GetAll()
+	for i := range sessions {
+		sessions[i].UserID = ""
+	}
+	if Get(s.ID).UserID == "" {
+		t.Error("Expected changes to the sessions returned not to change the store.")
+	}
+	Remove(s.ID)
+}
+
 func TestMain(m *testing.M) {
 	usernames := [4]string{"alex", "blake", "corey", "devon"}
 	for _, un := range usernames {
-		addr, err := mail.ParseAddress(strings.Title(un) + "<" + un + "@example.com>")
+		addr, err := mail.ParseAddress(cases.Title(language.Und).String(un) + "<" + un + "@example.com>")
 		if err != nil {
 			log.Fatal(err)
 		}
-		u := user.User{Username: un, Password: helpers.GeneratePasswordHash("s3cr3t"), Address: addr, Created: time.Now()}
-		u = user.Create(u)
-		Create(u.ID)
+		u := user.User{Username: un, Password: helpers.GeneratePasswordHash(context.Background(), "s3cr3t"), Address: addr, Created: time.Now()}
+		u = user.Create(context.Background(), u)
+		Create(context.Background(), u.ID)
 	}
 
 	os.Exit(m.Run())
@@ -0,0 +1,75 @@
+// Package tracing sets up OpenTelemetry tracing and starts spans.
+//
+// sn - https://github.com/sn
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/sn/service/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestSetup(t *testing.T) {
+	defer otel.SetTracerProvider(otel.GetTracerProvider())
+
+	var buf bytes.Buffer
+	c := config.Default().Tracing
+	c.Exporter = config.ExporterStdout
+	shutdown, err := Setup(c, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, span := Start(context.Background(), "test.span")
+	span.End()
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `"Name":"test.span"`) {
+		t.Errorf("Expected span to be written, got %q.", buf.String())
+	}
+
+	for _, exporter := range []string{config.ExporterNone, config.ExporterOTLP} {
+		c.Exporter = exporter
+		shutdown, err := Setup(c, &buf)
+		if err != nil {
+			t.Errorf("%s: %v", exporter, err)
+			continue
+		}
+		shutdown(context.Background())
+	}
+	c.Exporter = "jaeger"
+	if _, err := Setup(c, &buf); err == nil {
+		t.Error("Expected unknown exporter to fail.")
+	}
+}
+
+func TestStart(t *testing.T) {
+	defer otel.SetTracerProvider(otel.GetTracerProvider())
+	exporter := tracetest.NewInMemoryExporter()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+
+	carrier := propagation.MapCarrier{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}
+	ctx := propagation.TraceContext{}.Extract(context.Background(), carrier)
+	ctx, parent := Start(ctx, "parent")
+	_, child := Start(ctx, "child")
+	child.End()
+	parent.End()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("Expected two spans, got %d.", len(spans))
+	}
+	if spans[0].Parent.SpanID() != spans[1].SpanContext.SpanID() {
+		t.Error("Expected child span to have the parent span as parent.")
+	}
+	if spans[1].SpanContext.TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Error("Expected the remote trace to be continued.")
+	}
+}
@@ -3,5 +3,204 @@
 // sn - https://github.com/sn
 package types
 
-// UUID represents a universally unique identifier
+import (
+	"crypto/rand"
+	"database/sql/driver"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// UUID represents a universally unique identifier (RFC 4122) in its canonical
+// lowercase form, e.g. "6ba7b810-9dad-41d1-80b4-00c04fd430c8". The empty
+// UUID represents the absence of an identifier.
 type UUID string
+
+// FromBytes formats 16 bytes as a UUID
+func FromBytes(b [16]byte) UUID {
+	buf := make([]byte, 36)
+	hex.Encode(buf[0:8], b[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], b[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], b[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], b[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], b[10:])
+	return UUID(buf)
+}
+
+// Parse parses a UUID in the canonical form, with or without hyphens,
+// surrounded by braces, or prefixed by "urn:uuid:", in any case.
+func Parse(s string) (UUID, error) {
+	b, err := parse(s)
+	if err != nil {
+		return "", err
+	}
+	return FromBytes(b), nil
+}
+
+func parse(s string) ([16]byte, error) {
+	var b [16]byte
+	in := s
+	switch {
+	case len(s) == 45 && strings.EqualFold(s[:9], "urn:uuid:"):
+		s = s[9:]
+	case len(s) == 38 && s[0] == '{' && s[37] == '}':
+		s = s[1:37]
+	}
+	switch len(s) {
+	case 36:
+		if s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+			return b, fmt.Errorf("Invalid UUID: %q", in)
+		}
+		s = s[0:8] + s[9:13] + s[14:18] + s[19:23] + s[24:]
+	case 32:
+	default:
+		return b, fmt.Errorf("Invalid UUID length: %q", in)
+	}
+	if _, err := hex.Decode(b[:], []byte(s)); err != nil {
+		return b, fmt.Errorf("Invalid UUID: %q", in)
+	}
+	return b, nil
+}
+
+// Validate checks that the UUID is well formed
+func (u UUID) Validate() error {
+	_, err := parse(string(u))
+	return err
+}
+
+// Bytes returns the 16 bytes of the UUID
+func (u UUID) Bytes() ([16]byte, error) {
+	return parse(string(u))
+}
+
+// Version returns the version of the UUID, or 0 if it is malformed
+func (u UUID) Version() int {
+	b, err := parse(string(u))
+	if err != nil {
+		return 0
+	}
+	return int(b[6] >> 4)
+}
+
+// String returns the UUID as a string
+func (u UUID) String() string {
+	return string(u)
+}
+
+// MarshalText implements encoding.TextMarshaler, which is also used for JSON
+func (u UUID) MarshalText() ([]byte, error) {
+	if u == "" {
+		return []byte{}, nil
+	}
+	id, err := Parse(string(u))
+	if err != nil {
+		return nil, err
+	}
+	return []byte(id), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, which is also used for
+// JSON
+func (u *UUID) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*u = ""
+		return nil
+	}
+	id, err := Parse(string(text))
+	if err != nil {
+		return err
+	}
+	*u = id
+	return nil
+}
+
+// Value implements driver.Valuer, storing the UUID in its canonical form
+func (u UUID) Value() (driver.Value, error) {
+	if u == "" {
+		return nil, nil
+	}
+	id, err := Parse(string(u))
+	if err != nil {
+		return nil, err
+	}
+	return string(id), nil
+}
+
+// Scan implements sql.Scanner, accepting strings and 16 byte binary values
+func (u *UUID) Scan(src interface{}) error {
+	switch src := src.(type) {
+	case nil:
+		*u = ""
+		return nil
+	case string:
+		return u.UnmarshalText([]byte(src))
+	case []byte:
+		if len(src) == 16 {
+			var b [16]byte
+			copy(b[:], src)
+			*u = FromBytes(b)
+			return nil
+		}
+		return u.UnmarshalText(src)
+	}
+	return fmt.Errorf("Unable to scan %T into UUID", src)
+}
+
+// NewV4 generates a random (version 4) UUID
+func NewV4() (UUID, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return FromBytes(b), nil
+}
+
+var (
+	v7Mu      sync.Mutex
+	v7Last    int64
+	v7Counter uint16
+)
+
+// NewV7 generates a time-ordered (version 7) UUID. UUIDs generated by the
+// same process are strictly increasing, even within the same millisecond.
+func NewV7() (UUID, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+
+	v7Mu.Lock()
+	ms := time.Now().UnixNano() / int64(time.Millisecond)
+	if ms > v7Last {
+		v7Last = ms
+		v7Counter = binary.BigEndian.Uint16(b[6:8]) & 0x07ff
+	} else {
+		v7Counter++
+		if v7Counter > 0x0fff {
+			v7Last++
+			v7Counter = 0
+		}
+	}
+	ms, counter := v7Last, v7Counter
+	v7Mu.Unlock()
+
+	b[0] = byte(ms >> 40)
+	b[1] = byte(ms >> 32)
+	b[2] = byte(ms >> 24)
+	b[3] = byte(ms >> 16)
+	b[4] = byte(ms >> 8)
+	b[5] = byte(ms)
+	b[6] = 0x70 | byte(counter>>8)
+	b[7] = byte(counter)
+	b[8] = b[8]&0x3f | 0x80
+	return FromBytes(b), nil
+}
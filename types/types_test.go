@@ -0,0 +1,133 @@
+// Package types contains types that are used throughout the application.
+//
+// sn - https://github.com/sn
+package types
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+const canonical = "6ba7b810-9dad-41d1-80b4-00c04fd430c8"
+
+func TestParse(t *testing.T) {
+	valid := []string{
+		canonical,
+		"6BA7B810-9DAD-41D1-80B4-00C04FD430C8",
+		"6ba7b8109dad41d180b400c04fd430c8",
+		"{6ba7b810-9dad-41d1-80b4-00c04fd430c8}",
+		"urn:uuid:6ba7b810-9dad-41d1-80b4-00c04fd430c8",
+	}
+	for _, s := range valid {
+		u, err := Parse(s)
+		if err != nil {
+			t.Error(err)
+		}
+		if u != canonical {
+			t.Errorf("Expected %q to parse to the canonical form, got %q.", s, u)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"garbage",
+		"6ba7b810-9dad-41d1-80b4-00c04fd430c",
+		"6ba7b810x9dad-41d1-80b4-00c04fd430c8",
+		"6ba7b810-9dad-41d1-80b4-00c04fd430cg",
+	}
+	for _, s := range invalid {
+		if _, err := Parse(s); err == nil {
+			t.Errorf("Expected %q to fail to parse.", s)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := UUID(canonical).Validate(); err != nil {
+		t.Error(err)
+	}
+	if err := UUID("garbage").Validate(); err == nil {
+		t.Error("Expected malformed UUID to be invalid.")
+	}
+}
+
+func TestNewV4(t *testing.T) {
+	u, err := NewV4()
+	if err != nil {
+		t.Error(err)
+	}
+	if u.Version() != 4 {
+		t.Errorf("Expected version 4, got %d.", u.Version())
+	}
+	b, _ := u.Bytes()
+	if b[8]&0xc0 != 0x80 {
+		t.Error("Expected RFC 4122 variant.")
+	}
+	if v, _ := NewV4(); v == u {
+		t.Error("Expected unique UUIDs.")
+	}
+}
+
+func TestNewV7(t *testing.T) {
+	prev, err := NewV7()
+	if err != nil {
+		t.Error(err)
+	}
+	if prev.Version() != 7 {
+		t.Errorf("Expected version 7, got %d.", prev.Version())
+	}
+	for i := 0; i < 10000; i++ {
+		u, err := NewV7()
+		if err != nil {
+			t.Error(err)
+		}
+		if u <= prev {
+			t.Fatalf("Expected %q to sort after %q.", u, prev)
+		}
+		prev = u
+	}
+}
+
+func TestJSON(t *testing.T) {
+	var v struct{ ID UUID }
+	if err := json.Unmarshal([]byte(`{"ID":"6BA7B810-9DAD-41D1-80B4-00C04FD430C8"}`), &v); err != nil {
+		t.Error(err)
+	}
+	if v.ID != canonical {
+		t.Error("Expected JSON UUID to be canonicalized.")
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Error(err)
+	}
+	if string(b) != `{"ID":"`+canonical+`"}` {
+		t.Errorf("Unexpected JSON: %s", b)
+	}
+	if err := json.Unmarshal([]byte(`{"ID":"garbage"}`), &v); err == nil {
+		t.Error("Expected malformed JSON UUID to fail.")
+	}
+}
+
+func TestSQL(t *testing.T) {
+	var u UUID
+	if err := u.Scan(canonical); err != nil || u != canonical {
+		t.Error("Expected string to scan.")
+	}
+	b, _ := UUID(canonical).Bytes()
+	u = ""
+	if err := u.Scan(b[:]); err != nil || u != canonical {
+		t.Error("Expected binary value to scan.")
+	}
+	if err := u.Scan(nil); err != nil || u != "" {
+		t.Error("Expected NULL to scan to the empty UUID.")
+	}
+	if err := u.Scan(42); err == nil {
+		t.Error("Expected unsupported type to fail.")
+	}
+	if v, err := UUID(canonical).Value(); err != nil || v != canonical {
+		t.Error("Expected canonical value.")
+	}
+	if v, err := UUID("").Value(); err != nil || v != nil {
+		t.Error("Expected NULL value.")
+	}
+}
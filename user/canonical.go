@@ -0,0 +1,88 @@
+// Package user manages the users for the application.
+//
+// sn - https://github.com/sn
+package user
+
+import (
+	"strings"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// AddressRule rewrites the local part of an address for a given provider
+type AddressRule func(local string) string
+
+// AddressRules maps a lowercased domain to the provider-specific rule applied
+// to local parts at that domain. Remove entries to disable a rule.
+var AddressRules = map[string]AddressRule{
+	"gmail.com":      gmailRule,
+	"googlemail.com": gmailRule,
+}
+
+// AddressDomainAliases maps a lowercased domain to the domain it is an alias of
+var AddressDomainAliases = map[string]string{
+	"googlemail.com": "gmail.com",
+}
+
+// gmailRule ignores dots and anything after a plus sign
+func gmailRule(local string) string {
+	if i := strings.Index(local, "+"); i >= 0 {
+		local = local[:i]
+	}
+	return strings.Replace(local, ".", "", -1)
+}
+
+// confusables maps characters to the character they are commonly mistaken
+// for. It is a subset of the Unicode confusables data (UTS #39) covering
+// Latin lookalikes in the scripts and digits we see in practice.
+var confusables = map[rune]string{
+	'0': "o", '1': "l", '|': "l", 'ı': "i", 'ł': "l",
+	// Cyrillic
+	'а': "a", 'е': "e", 'һ': "h", 'і': "i", 'ј': "j", 'о': "o", 'р': "p",
+	'с': "c", 'у': "y", 'х': "x", 'ѕ': "s", 'ԁ': "d", 'ԛ': "q", 'ԝ': "w",
+	// Greek
+	'α': "a", 'ι': "i", 'ν': "v", 'ο': "o", 'ρ': "p", 'χ': "x",
+	// Latin
+	'ɡ': "g", 'ɑ': "a", 'ɩ': "i", 'ʋ': "u",
+}
+
+// CanonicalUsername returns the form of a username used to compare usernames,
+// which is the NFKC normalized, case folded username.
+func CanonicalUsername(username string) string {
+	return norm.NFKC.String(cases.Fold().String(norm.NFKC.String(username)))
+}
+
+// UsernameSkeleton returns the confusable skeleton of a username. Two
+// usernames with the same skeleton are visually confusable with each other.
+func UsernameSkeleton(username string) string {
+	canonical := norm.NFD.String(CanonicalUsername(username))
+	var b strings.Builder
+	for _, r := range canonical {
+		if s, ok := confusables[r]; ok {
+			b.WriteString(s)
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return strings.Replace(norm.NFD.String(b.String()), "rn", "m", -1)
+}
+
+// CanonicalAddress returns the form of an email address used to compare
+// addresses. The domain is lowercased, and the local part is case folded and
+// rewritten by any rule in AddressRules for the domain.
+func CanonicalAddress(address string) string {
+	i := strings.LastIndex(address, "@")
+	if i < 0 {
+		return strings.ToLower(address)
+	}
+	local := cases.Fold().String(address[:i])
+	domain := strings.ToLower(address[i+1:])
+	if rule, ok := AddressRules[domain]; ok {
+		local = rule(local)
+	}
+	if alias, ok := AddressDomainAliases[domain]; ok {
+		domain = alias
+	}
+	return local + "@" + domain
+}
@@ -0,0 +1,56 @@
+// Package user manages the users for the application.
+//
+// sn - https://github.com/sn
+package user
+
+import "testing"
+
+func TestCanonicalUsername(t *testing.T) {
+	cases := map[string]string{
+		"alex":   "alex",
+		"Alex":   "alex",
+		"ALEX":   "alex",
+		"ＡＬＥＸ":   "alex",
+		"Straße": "strasse",
+		"ﬁnn":    "finn",
+	}
+	for in, out := range cases {
+		if c := CanonicalUsername(in); c != out {
+			t.Errorf("Expected %q to canonicalize to %q, got %q.", in, out, c)
+		}
+	}
+}
+
+func TestUsernameSkeleton(t *testing.T) {
+	confusable := [][2]string{
+		{"alex", "a1ex"},
+		{"alex", "аlex"},   // Cyrillic a
+		{"corey", "cοrey"}, // Greek omicron
+		{"devon", "dev0n"},
+		{"modern", "rnodern"},
+	}
+	for _, c := range confusable {
+		if UsernameSkeleton(c[0]) != UsernameSkeleton(c[1]) {
+			t.Errorf("Expected %q to be confusable with %q.", c[0], c[1])
+		}
+	}
+	if UsernameSkeleton("alex") == UsernameSkeleton("alèx") {
+		t.Error("Expected accented username to be distinct.")
+	}
+}
+
+func TestCanonicalAddress(t *testing.T) {
+	cases := map[string]string{
+		"alex@example.com":         "alex@example.com",
+		"Alex@Example.com":         "alex@example.com",
+		"a.l.e.x+news@gmail.com":   "alex@gmail.com",
+		"Alex@GoogleMail.com":      "alex@gmail.com",
+		"a.l.e.x+news@example.com": "a.l.e.x+news@example.com",
+		"no-domain":                "no-domain",
+	}
+	for in, out := range cases {
+		if c := CanonicalAddress(in); c != out {
+			t.Errorf("Expected %q to canonicalize to %q, got %q.", in, out, c)
+		}
+	}
+}
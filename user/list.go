@@ -0,0 +1,204 @@
+// Package user manages the users for the application.
+//
+// sn - https://github.com/sn
+package user
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sn/service/tracing"
+	"github.com/sn/service/types"
+)
+
+// Sort orders for List. Prefix with "-" to sort in descending order.
+const (
+	SortCreated  = "created"
+	SortUsername = "username"
+)
+
+var (
+	// DefaultLimit is the page size used when a query has no limit
+	DefaultLimit = 50
+
+	// MaxLimit is the largest page size a query can ask for
+	MaxLimit = 200
+)
+
+// ErrInvalidCursor is returned when a cursor is malformed or does not belong
+// to the query
+var ErrInvalidCursor = fmt.Errorf("Invalid cursor")
+
+// Query describes a page of users to list
+type Query struct {
+	Limit          int
+	Cursor         string
+	UsernamePrefix string
+	CreatedAfter   time.Time
+	CreatedBefore  time.Time
+	Role           string
+	Sort           string
+}
+
+// Page is a page of users, with the cursors of the pages around it. A cursor
+// is empty if there is no page in that direction.
+type Page struct {
+	Users []User
+	Next  string
+	Prev  string
+}
+
+// cursor is the decoded form of a page cursor: a position in a sort order,
+// and whether the page is before or after it
+type cursor struct {
+	Sort   string     `json:"s"`
+	Key    string     `json:"k"`
+	ID     types.UUID `json:"i"`
+	Before bool       `json:"b,omitempty"`
+}
+
+func (c cursor) encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string) (cursor, error) {
+	c := cursor{}
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(b, &c); err != nil {
+		return c, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// sortKey returns the key a user is sorted by
+func sortKey(u User, order string) string {
+	if order == SortUsername {
+		return CanonicalUsername(u.Username)
+	}
+	return u.Created.UTC().Format("2006-01-02T15:04:05.000000000Z")
+}
+
+// ValidSort checks whether a sort order is supported by List
+func ValidSort(s string) bool {
+	switch strings.TrimPrefix(s, "-") {
+	case SortCreated, SortUsername:
+		return true
+	}
+	return false
+}
+
+// List returns a page of users matching a query. Users are sorted by creation
+// time unless the query says otherwise, with ties broken by ID.
+func List(ctx context.Context, q Query) (Page, error) {
+	_, span := tracing.Start(ctx, "user.List")
+	defer span.End()
+	if q.Sort == "" {
+		q.Sort = SortCreated
+	}
+	if !ValidSort(q.Sort) {
+		return Page{}, fmt.Errorf("Invalid sort: %q", q.Sort)
+	}
+	if q.Limit <= 0 {
+		q.Limit = DefaultLimit
+	}
+	if q.Limit > MaxLimit {
+		q.Limit = MaxLimit
+	}
+	order := strings.TrimPrefix(q.Sort, "-")
+	desc := strings.HasPrefix(q.Sort, "-")
+
+	var at *cursor
+	if q.Cursor != "" {
+		c, err := decodeCursor(q.Cursor)
+		if err != nil {
+			return Page{}, err
+		}
+		if c.Sort != q.Sort {
+			return Page{}, ErrInvalidCursor
+		}
+		at = &c
+	}
+
+	prefix := CanonicalUsername(q.UsernamePrefix)
+	type entry struct {
+		key  string
+		user User
+	}
+	var entries []entry
+	mu.RLock()
+	for _, u := range users {
+		if prefix != "" && !strings.HasPrefix(CanonicalUsername(u.Username), prefix) {
+			continue
+		}
+		if !q.CreatedAfter.IsZero() && !u.Created.After(q.CreatedAfter) {
+			continue
+		}
+		if !q.CreatedBefore.IsZero() && !u.Created.Before(q.CreatedBefore) {
+			continue
+		}
+		if q.Role != "" && u.Role != q.Role {
+			continue
+		}
+		entries = append(entries, entry{sortKey(u, order), u})
+	}
+	mu.RUnlock()
+
+	// compare orders a key and ID against another in the query's sort order
+	compare := func(key string, id types.UUID, otherKey string, otherID types.UUID) int {
+		c := strings.Compare(key, otherKey)
+		if c == 0 {
+			c = strings.Compare(string(id), string(otherID))
+		}
+		if desc {
+			return -c
+		}
+		return c
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return compare(entries[i].key, entries[i].user.ID, entries[j].key, entries[j].user.ID) < 0
+	})
+
+	start, end := 0, len(entries)
+	if at != nil {
+		// The first entry sorting after the cursor, or at it when paging
+		// backwards
+		pos := sort.Search(len(entries), func(i int) bool {
+			c := compare(entries[i].key, entries[i].user.ID, at.Key, at.ID)
+			return c > 0 || (c == 0 && at.Before)
+		})
+		if at.Before {
+			end = pos
+			if start = end - q.Limit; start < 0 {
+				start = 0
+			}
+		} else {
+			start = pos
+		}
+	}
+	if end-start > q.Limit {
+		end = start + q.Limit
+	}
+
+	page := Page{Users: []User{}}
+	for _, e := range entries[start:end] {
+		page.Users = append(page.Users, e.user)
+	}
+	if end < len(entries) && end > start {
+		last := entries[end-1]
+		page.Next = cursor{Sort: q.Sort, Key: last.key, ID: last.user.ID}.encode()
+	}
+	if start > 0 && start < len(entries) {
+		first := entries[start]
+		page.Prev = cursor{Sort: q.Sort, Key: first.key, ID: first.user.ID, Before: true}.encode()
+	}
+	return page, nil
+}
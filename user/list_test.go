@@ -0,0 +1,90 @@
+// Package user manages the users for the application.
+//
+// sn - https://github.com/sn
+package user
+
+import (
+	"context"
+	"net/mail"
+	"testing"
+	"time"
+)
+
+func TestList(t *testing.T) {
+	before := time.Now()
+	var created []User
+	for _, un := range []string{"listd", "listb", "listc", "lista", "liste"} {
+		address, err := mail.ParseAddress(un + "@example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+		created = append(created, Create(context.Background(), User{Username: un, Password: "S3crET!@#$", Address: address}))
+	}
+	Patch(context.Background(), User{ID: created[4].ID, Role: RoleAdmin})
+
+	page, err := List(context.Background(), Query{UsernamePrefix: "LIST", Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var seen []User
+	for {
+		seen = append(seen, page.Users...)
+		if page.Next == "" {
+			break
+		}
+		if page, err = List(context.Background(), Query{UsernamePrefix: "list", Limit: 2, Cursor: page.Next}); err != nil {
+			t.Fatal(err)
+		}
+		if page.Prev == "" {
+			t.Error("Expected previous page cursor.")
+		}
+	}
+	if len(seen) != len(created) {
+		t.Fatalf("Expected %d users, got %d.", len(created), len(seen))
+	}
+	for i := range created {
+		if seen[i].ID != created[i].ID {
+			t.Error("Expected users in creation order.")
+		}
+	}
+
+	// Page backwards from the last page
+	if page, err = List(context.Background(), Query{UsernamePrefix: "list", Limit: 2, Cursor: page.Prev}); err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Users) != 2 || page.Users[0].ID != created[2].ID || page.Users[1].ID != created[3].ID {
+		t.Error("Incorrect previous page.")
+	}
+
+	page, err = List(context.Background(), Query{UsernamePrefix: "list", Sort: "-username"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Users) != 5 || page.Users[0].Username != "liste" || page.Users[4].Username != "lista" {
+		t.Error("Expected users in descending username order.")
+	}
+
+	page, _ = List(context.Background(), Query{UsernamePrefix: "list", Role: RoleAdmin})
+	if len(page.Users) != 1 || page.Users[0].ID != created[4].ID {
+		t.Error("Expected role filter to match one user.")
+	}
+	page, _ = List(context.Background(), Query{UsernamePrefix: "list", CreatedAfter: before, CreatedBefore: created[2].Created})
+	if len(page.Users) != 2 {
+		t.Error("Expected created filters to match two users.")
+	}
+
+	if _, err := List(context.Background(), Query{Sort: "password"}); err == nil {
+		t.Error("Expected invalid sort to fail.")
+	}
+	if _, err := List(context.Background(), Query{Cursor: "garbage"}); err != ErrInvalidCursor {
+		t.Error("Expected invalid cursor to fail.")
+	}
+	next, _ := List(context.Background(), Query{Limit: 1})
+	if _, err := List(context.Background(), Query{Sort: "username", Cursor: next.Next}); err != ErrInvalidCursor {
+		t.Error("Expected cursor from another sort order to fail.")
+	}
+
+	for _, u := range created {
+		Delete(context.Background(), u.ID)
+	}
+}
@@ -0,0 +1,113 @@
+// Package user manages the users for the application.
+//
+// sn - https://github.com/sn
+package user
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+)
+
+// ErrUsernameReserved is returned when a username is reserved or banned
+var ErrUsernameReserved = fmt.Errorf("Username is reserved.")
+
+// DefaultReserved contains the usernames reserved out of the box: names that
+// impersonate staff or the system, and names that collide with routes.
+var DefaultReserved = []string{
+	"abuse", "admin", "administrator", "anonymous", "api", "auth", "help",
+	"hostmaster", "info", "mod", "moderator", "null", "official", "owner",
+	"postmaster", "root", "security", "staff", "support", "sysadmin",
+	"system", "undefined", "user", "users", "webmaster", "www",
+}
+
+var (
+	reservedMu sync.RWMutex
+	reserved   = map[string]string{}
+	banned     = map[string]*regexp.Regexp{}
+)
+
+func init() {
+	Reserve(DefaultReserved...)
+}
+
+// Reserve adds usernames to the reserved list. Names confusable with a
+// reserved name are reserved too.
+func Reserve(names ...string) {
+	reservedMu.Lock()
+	defer reservedMu.Unlock()
+	for _, name := range names {
+		reserved[UsernameSkeleton(name)] = name
+	}
+}
+
+// Unreserve removes usernames from the reserved list
+func Unreserve(names ...string) {
+	reservedMu.Lock()
+	defer reservedMu.Unlock()
+	for _, name := range names {
+		delete(reserved, UsernameSkeleton(name))
+	}
+}
+
+// Reserved returns the reserved usernames in alphabetical order
+func Reserved() []string {
+	reservedMu.RLock()
+	defer reservedMu.RUnlock()
+	names := make([]string, 0, len(reserved))
+	for _, name := range reserved {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Ban bans any username whose canonical form matches the regular expression,
+// ignoring case. A plain word bans every username containing it.
+func Ban(pattern string) error {
+	re, err := regexp.Compile("(?i)" + pattern)
+	if err != nil {
+		return err
+	}
+	reservedMu.Lock()
+	defer reservedMu.Unlock()
+	banned[pattern] = re
+	return nil
+}
+
+// Unban removes a pattern added by Ban
+func Unban(pattern string) {
+	reservedMu.Lock()
+	defer reservedMu.Unlock()
+	delete(banned, pattern)
+}
+
+// Banned returns the banned patterns in alphabetical order
+func Banned() []string {
+	reservedMu.RLock()
+	defer reservedMu.RUnlock()
+	patterns := make([]string, 0, len(banned))
+	for pattern := range banned {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+	return patterns
+}
+
+// IsReserved checks whether a username is reserved or banned
+func IsReserved(username string) bool {
+	reservedMu.RLock()
+	defer reservedMu.RUnlock()
+	if _, ok := reserved[UsernameSkeleton(username)]; ok {
+		return true
+	}
+	canonical := CanonicalUsername(username)
+	skeleton := UsernameSkeleton(username)
+	for _, re := range banned {
+		if re.MatchString(canonical) || re.MatchString(skeleton) {
+			return true
+		}
+	}
+	return false
+}
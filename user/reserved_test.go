@@ -0,0 +1,67 @@
+// Package user manages the users for the application.
+//
+// sn - https://github.com/sn
+package user
+
+import "testing"
+
+func TestReserve(t *testing.T) {
+	if !IsReserved("Admin") {
+		t.Error("Expected default reserved name to be reserved.")
+	}
+	if !IsReserved("аdmin") { // Cyrillic a
+		t.Error("Expected name confusable with a reserved name to be reserved.")
+	}
+	Reserve("zgadmin")
+	if !IsReserved("ZGAdmin") {
+		t.Error("Expected added name to be reserved.")
+	}
+	Unreserve("zgadmin")
+	if IsReserved("zgadmin") {
+		t.Error("Expected removed name to not be reserved.")
+	}
+}
+
+func TestReserved(t *testing.T) {
+	names := Reserved()
+	if len(names) != len(DefaultReserved) {
+		t.Error("Incorrect reserved names length.")
+	}
+}
+
+func TestBan(t *testing.T) {
+	if err := Ban("("); err == nil {
+		t.Error("Expected invalid pattern to fail.")
+	}
+	if err := Ban("badword"); err != nil {
+		t.Error(err)
+	}
+	if !IsReserved("xxBADWORDxx") {
+		t.Error("Expected name containing banned word to be banned.")
+	}
+	if !IsReserved("badw0rd") {
+		t.Error("Expected name confusable with banned word to be banned.")
+	}
+	if len(Banned()) != 1 {
+		t.Error("Incorrect banned patterns length.")
+	}
+	Unban("badword")
+	if IsReserved("xxbadwordxx") {
+		t.Error("Expected unbanned name to be allowed.")
+	}
+
+	if err := Ban("^Spam"); err != nil {
+		t.Error(err)
+	}
+	if !IsReserved("spammer") {
+		t.Error("Expected patterns to ignore case.")
+	}
+	Unban("^Spam")
+}
+
+func TestValidateReserved(t *testing.T) {
+	u := User{Username: "Support", Password: "@1z34S6789"}
+	if err := Validate(u); err != ErrUsernameReserved {
+		t.Error("Expected reserved username error.")
+	}
+}
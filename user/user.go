@@ -4,39 +4,112 @@
 package user
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
 	"net/mail"
 	"regexp"
+	"sync"
 	"time"
 
+	"github.com/sn/service/config"
+	"github.com/sn/service/health"
 	"github.com/sn/service/helpers"
+	"github.com/sn/service/metrics"
+	"github.com/sn/service/tracing"
 	"github.com/sn/service/types"
 )
 
 // User represents a user
 type User struct {
-	ID       types.UUID
-	Username string
-	Password string
-	Address  *mail.Address
-	Created  time.Time
-	Updated  time.Time
+	ID          types.UUID
+	Username    string
+	Password    string `json:"-"`
+	Address     *mail.Address
+	Role        string
+	Deactivated bool
+	Created     time.Time
+	Updated     time.Time
+	Version     int
 }
 
-var users []User
+// Roles a user can have
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// ErrNotFound is returned when a user does not exist
+var ErrNotFound = fmt.Errorf("Not found")
+
+// ErrUsernameTaken is returned when a username is confusable with another
+// user's
+var ErrUsernameTaken = fmt.Errorf("Username is taken.")
+
+// ErrAddressTaken is returned when an address is the same as another user's
+var ErrAddressTaken = fmt.Errorf("Address is taken.")
+
+// ErrVersionMismatch is returned when a user has changed since it was read
+var ErrVersionMismatch = fmt.Errorf("Version mismatch")
+
+var (
+	mu    sync.RWMutex
+	users []User
+)
+
+// Configure applies the user configuration
+func Configure(c config.User) error {
+	DefaultLimit = c.PageSize
+	MaxLimit = c.MaxPageSize
+	helpers.Scrypt = helpers.ScryptParams{N: c.Scrypt.N, R: c.Scrypt.R, P: c.Scrypt.P, KeyLen: c.Scrypt.KeyLen}
+	Reserve(c.Reserved...)
+	for _, pattern := range c.Banned {
+		if err := Ban(pattern); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LogValue logs a user without its password hash or address
+func (u User) LogValue() slog.Value {
+	return slog.GroupValue(slog.String("id", string(u.ID)), slog.String("username", u.Username), slog.String("role", u.Role))
+}
 
 // CheckPassword validates a password
-func CheckPassword(u User, password string) bool {
-	return u.Password == helpers.GeneratePasswordHash(password)
+func CheckPassword(ctx context.Context, u User, password string) bool {
+	return u.Password == helpers.GeneratePasswordHash(ctx, password)
+}
+
+func init() {
+	metrics.GaugeFunc("users", "Users, including deactivated users.", func() float64 { return float64(Count()) })
 }
 
-// GetAll returns all users
+// Count returns the number of users
+func Count() int {
+	mu.RLock()
+	defer mu.RUnlock()
+	return len(users)
+}
+
+// Ping checks that the store can be read, for readiness checks
+func Ping(ctx context.Context) error {
+	return health.ProbeLock(ctx, &mu, "User store")
+}
+
+// GetAll returns a copy of all users
 func GetAll() []User {
-	return users
+	mu.RLock()
+	defer mu.RUnlock()
+	return append([]User(nil), users...)
 }
 
 // FindByID looks for a user given a UUID
-func FindByID(id types.UUID) User {
+func FindByID(ctx context.Context, id types.UUID) User {
+	_, span := tracing.Start(ctx, "user.FindByID")
+	defer span.End()
+	mu.RLock()
+	defer mu.RUnlock()
 	for _, u := range users {
 		if u.ID == id {
 			return u
@@ -45,20 +118,57 @@ func FindByID(id types.UUID) User {
 	return User{}
 }
 
-// FindByAddress finds a user by address
-func FindByAddress(address *mail.Address) User {
+// FindByAddress finds a user by address, comparing canonical addresses
+func FindByAddress(ctx context.Context, address *mail.Address) User {
+	_, span := tracing.Start(ctx, "user.FindByAddress")
+	defer span.End()
+	if address == nil {
+		return User{}
+	}
+	mu.RLock()
+	defer mu.RUnlock()
+	return findByAddress(address)
+}
+
+func findByAddress(address *mail.Address) User {
+	canonical := CanonicalAddress(address.Address)
+	for _, u := range users {
+		if u.Address != nil && CanonicalAddress(u.Address.Address) == canonical {
+			return u
+		}
+	}
+	return User{}
+}
+
+// FindByUsername finds a user by username, comparing canonical usernames
+func FindByUsername(ctx context.Context, username string) User {
+	_, span := tracing.Start(ctx, "user.FindByUsername")
+	defer span.End()
+	canonical := CanonicalUsername(username)
+	mu.RLock()
+	defer mu.RUnlock()
 	for _, u := range users {
-		if u.Address.Address == address.Address {
+		if CanonicalUsername(u.Username) == canonical {
 			return u
 		}
 	}
 	return User{}
 }
 
-// FindByUsername finds a user by username
-func FindByUsername(username string) User {
+// FindByUsernameSkeleton finds a user whose username is confusable with the
+// given username
+func FindByUsernameSkeleton(ctx context.Context, username string) User {
+	_, span := tracing.Start(ctx, "user.FindByUsernameSkeleton")
+	defer span.End()
+	mu.RLock()
+	defer mu.RUnlock()
+	return findByUsernameSkeleton(username)
+}
+
+func findByUsernameSkeleton(username string) User {
+	skeleton := UsernameSkeleton(username)
 	for _, u := range users {
-		if u.Username == username {
+		if UsernameSkeleton(u.Username) == skeleton {
 			return u
 		}
 	}
@@ -68,7 +178,9 @@ func FindByUsername(username string) User {
 // Validate validates a username, password, and email
 //
 // A user is valid if:
-// - the username contains only alphanumerical characters,
+// - the username starts with a letter or number,
+// - the username contains only letters, combining marks, and numbers,
+// - the username is not reserved or banned (ErrUsernameReserved),
 // - the password
 //   - is longer than 10 characters,
 //   - contains at least one digit,
@@ -76,10 +188,13 @@ func FindByUsername(username string) User {
 //   - contains at least one uppercase letter,
 //   - contains at least one special character.
 func Validate(user User) error {
-	usernameRegex := regexp.MustCompile(`^[[:alnum:]]+$`)
+	usernameRegex := regexp.MustCompile(`^[\p{L}\p{N}][\p{L}\p{M}\p{N}]*$`)
 	if len(user.Username) > 0 && !usernameRegex.MatchString(user.Username) {
 		return fmt.Errorf("Username is invalid.")
 	}
+	if len(user.Username) > 0 && IsReserved(user.Username) {
+		return ErrUsernameReserved
+	}
 
 	if len(user.Password) > 0 {
 		length := regexp.MustCompile(`.{10,}`)
@@ -108,42 +223,142 @@ func Validate(user User) error {
 }
 
 // Create adds a user to the users list
-func Create(user User) User {
+func Create(ctx context.Context, user User) User {
+	ctx, span := tracing.Start(ctx, "user.Create")
+	defer span.End()
+	user = newUser(ctx, user)
+	mu.Lock()
+	defer mu.Unlock()
+	users = append(users, user)
+	return user
+}
+
+// CreateAdmin creates the configured admin, if any, with the admin role. Only
+// admins can give users the admin role, so the first admin is created this way.
+func CreateAdmin(ctx context.Context, c config.Admin) (User, error) {
+	if c.Username == "" {
+		return User{}, nil
+	}
+	address, err := mail.ParseAddress(c.Email)
+	if err != nil {
+		return User{}, fmt.Errorf("Unable to parse admin address: %v", err)
+	}
+	u := User{Username: c.Username, Password: c.Password, Address: address, Role: RoleAdmin}
+	if err := Validate(u); err != nil {
+		return User{}, err
+	}
+	return CreateUnique(ctx, u)
+}
+
+// CreateUnique adds a user to the users list unless its username or address
+// is taken (ErrUsernameTaken, ErrAddressTaken)
+func CreateUnique(ctx context.Context, user User) (User, error) {
+	ctx, span := tracing.Start(ctx, "user.CreateUnique")
+	defer span.End()
+	user = newUser(ctx, user)
+	mu.Lock()
+	defer mu.Unlock()
+	if len(findByUsernameSkeleton(user.Username).ID) > 0 {
+		return User{}, ErrUsernameTaken
+	}
+	if user.Address != nil && len(findByAddress(user.Address).ID) > 0 {
+		return User{}, ErrAddressTaken
+	}
+	users = append(users, user)
+	return user, nil
+}
+
+// newUser sets the generated fields of a new user
+func newUser(ctx context.Context, user User) User {
 	user.ID = helpers.GenerateUUID()
-	user.Password = helpers.GeneratePasswordHash(user.Password)
+	user.Password = helpers.GeneratePasswordHash(ctx, user.Password)
+	if user.Role == "" {
+		user.Role = RoleUser
+	}
 	user.Created = time.Now()
-	users = append(users, user)
+	user.Version = 1
 	return user
 }
 
-// Update updates a user in the users list based on the user ID
-func Update(user User) User {
-	user.Password = helpers.GeneratePasswordHash(user.Password)
+// Update updates a user in the users list based on the user ID, keeping its
+// role unless one is given, and whether it is deactivated. If the version is
+// set, the user is only updated if it is still at that version,
+// and ErrVersionMismatch is returned otherwise.
+func Update(ctx context.Context, user User) (User, error) {
+	ctx, span := tracing.Start(ctx, "user.Update")
+	defer span.End()
+	user.Password = helpers.GeneratePasswordHash(ctx, user.Password)
+	mu.Lock()
+	defer mu.Unlock()
 	for i, u := range users {
 		if u.ID == user.ID {
+			if user.Version != 0 && user.Version != u.Version {
+				return User{}, ErrVersionMismatch
+			}
+			if user.Role == "" {
+				user.Role = u.Role
+			}
+			user.Deactivated = u.Deactivated
+			user.Created = u.Created
 			user.Updated = time.Now()
+			user.Version = u.Version + 1
 			users[i] = user
-			return users[i]
+			return users[i], nil
 		}
 	}
-	return User{}
+	return User{}, ErrNotFound
 }
 
-// Patch patches a user in the users list based on the user ID
-func Patch(user User) User {
+// Patch patches a user in the users list based on the user ID, leaving empty
+// fields unchanged. If the version is set, the user is only patched if it is
+// still at that version, and ErrVersionMismatch is returned otherwise.
+func Patch(ctx context.Context, user User) (User, error) {
+	ctx, span := tracing.Start(ctx, "user.Patch")
+	defer span.End()
+	if user.Password != "" {
+		user.Password = helpers.GeneratePasswordHash(ctx, user.Password)
+	}
+	mu.Lock()
+	defer mu.Unlock()
 	for i, u := range users {
 		if u.ID == user.ID {
-			if user.Address.Address != "" {
+			if user.Version != 0 && user.Version != u.Version {
+				return User{}, ErrVersionMismatch
+			}
+			if user.Address != nil && user.Address.Address != "" {
 				u.Address = user.Address
 			}
 			if user.Username != "" {
 				u.Username = user.Username
 			}
 			if user.Password != "" {
-				u.Password = helpers.GeneratePasswordHash(user.Password)
+				u.Password = user.Password
+			}
+			if user.Role != "" {
+				u.Role = user.Role
 			}
 			u.Updated = time.Now()
+			u.Version++
 			users[i] = u
+			return users[i], nil
+		}
+	}
+	return User{}, ErrNotFound
+}
+
+// Deactivate deactivates a user based on the user ID
+func Deactivate(ctx context.Context, id types.UUID) User {
+	_, span := tracing.Start(ctx, "user.Deactivate")
+	defer span.End()
+	mu.Lock()
+	defer mu.Unlock()
+	for i, u := range users {
+		if u.ID == id {
+			if !u.Deactivated {
+				users[i].Deactivated = true
+				users[i].Updated = time.Now()
+				users[i].Version++
+			}
 			return users[i]
 		}
 	}
@@ -151,12 +366,25 @@ func Patch(user User) User {
 }
 
 // Delete deletes a user based on the user ID
-func Delete(id types.UUID) error {
+func Delete(ctx context.Context, id types.UUID) error {
+	return DeleteVersion(ctx, id, 0)
+}
+
+// DeleteVersion deletes a user based on the user ID if it is still at the
+// given version. A version of 0 deletes the user unconditionally.
+func DeleteVersion(ctx context.Context, id types.UUID, version int) error {
+	_, span := tracing.Start(ctx, "user.DeleteVersion")
+	defer span.End()
+	mu.Lock()
+	defer mu.Unlock()
 	for i, u := range users {
 		if u.ID == id {
+			if version != 0 && version != u.Version {
+				return ErrVersionMismatch
+			}
 			users = append(users[:i], users[i+1:]...)
 			return nil
 		}
 	}
-	return fmt.Errorf("Not found")
+	return ErrNotFound
 }
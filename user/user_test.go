@@ -4,31 +4,65 @@
 package user
 
 import (
+	"context"
 	"log"
+	"log/slog"
 	"net/mail"
 	"os"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/sn/service/config"
 	"github.com/sn/service/helpers"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
 )
 
 func TestCheckPassword(t *testing.T) {
 	users := GetAll()
-	u := FindByID(users[0].ID)
+	u := FindByID(context.Background(), users[0].ID)
 	correctPassword := "1@E4s67890"
 	incorrectPassword := "s3cret"
 
-	if !CheckPassword(u, correctPassword) {
+	if !CheckPassword(context.Background(), u, correctPassword) {
 		t.Error("Expected password success, got failure.")
 	}
 
-	if CheckPassword(u, incorrectPassword) {
+	if CheckPassword(context.Background(), u, incorrectPassword) {
 		t.Error("Expected password failure, got success.")
 	}
 }
 
+func TestLogValue(t *testing.T) {
+	u := GetAll()[0]
+	var buf strings.Builder
+	slog.New(slog.NewJSONHandler(&buf, nil)).Info("user", "user", u)
+	if !strings.Contains(buf.String(), string(u.ID)) || strings.Contains(buf.String(), u.Password) || strings.Contains(buf.String(), u.Address.Address) {
+		t.Errorf("Expected the user without password or address, got %s.", buf.String())
+	}
+}
+
+func TestCount(t *testing.T) {
+	if Count() != len(GetAll()) {
+		t.Error("Expected count to match all users.")
+	}
+}
+
+func TestPing(t *testing.T) {
+	if err := Ping(context.Background()); err != nil {
+		t.Error(err)
+	}
+	mu.Lock()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := Ping(ctx)
+	mu.Unlock()
+	if err == nil {
+		t.Error("Expected a locked store to be unavailable.")
+	}
+}
+
 func TestGetAll(t *testing.T) {
 	users := GetAll()
 	if len(users) == 0 {
@@ -41,11 +75,11 @@ func TestFindByID(t *testing.T) {
 	knownID := users[0].ID
 	unknownID := helpers.GenerateUUID()
 
-	if u := FindByID(knownID); len(u.ID) == 0 {
+	if u := FindByID(context.Background(), knownID); len(u.ID) == 0 {
 		t.Error("Expected known user ID, got unknown user ID.")
 	}
 
-	if u := FindByID(unknownID); len(u.ID) > 0 {
+	if u := FindByID(context.Background(), unknownID); len(u.ID) > 0 {
 		t.Error("Expected unknown user ID, got known user ID.")
 	}
 }
@@ -61,11 +95,19 @@ func TestFindByAddress(t *testing.T) {
 		t.Error(err)
 	}
 
-	if u := FindByAddress(knownAddress); len(u.ID) == 0 {
+	if u := FindByAddress(context.Background(), knownAddress); len(u.ID) == 0 {
 		t.Error("Expected known address, got unknown address.")
 	}
 
-	if u := FindByAddress(unknownAddress); len(u.ID) > 0 {
+	upperAddress, err := mail.ParseAddress(strings.ToUpper(users[0].Address.Address))
+	if err != nil {
+		t.Error(err)
+	}
+	if u := FindByAddress(context.Background(), upperAddress); u.ID != users[0].ID {
+		t.Error("Expected address lookup to ignore case.")
+	}
+
+	if u := FindByAddress(context.Background(), unknownAddress); len(u.ID) > 0 {
 		t.Error("Expected unknown address, got known address.")
 	}
 }
@@ -75,11 +117,27 @@ func TestFindByUsername(t *testing.T) {
 	knownUsername := users[0].Username
 	unknownUsername := "unknown-username"
 
-	if u := FindByUsername(knownUsername); len(u.ID) == 0 {
+	if u := FindByUsername(context.Background(), knownUsername); len(u.ID) == 0 {
 		t.Error("Expected known user, got unknown user.")
 	}
 
-	if u := FindByUsername(unknownUsername); len(u.ID) > 0 {
+	if u := FindByUsername(context.Background(), strings.ToUpper(knownUsername)); u.ID != users[0].ID {
+		t.Error("Expected username lookup to ignore case.")
+	}
+
+	if u := FindByUsername(context.Background(), unknownUsername); len(u.ID) > 0 {
+		t.Error("Expected unknown user, got known user.")
+	}
+}
+
+func TestFindByUsernameSkeleton(t *testing.T) {
+	users := GetAll()
+
+	if u := FindByUsernameSkeleton(context.Background(), "а1ех"); u.ID != users[0].ID { // Cyrillic a and x
+		t.Error("Expected confusable user, got unknown user.")
+	}
+
+	if u := FindByUsernameSkeleton(context.Background(), "unknown"); len(u.ID) > 0 {
 		t.Error("Expected unknown user, got known user.")
 	}
 }
@@ -97,6 +155,18 @@ func TestValidate(t *testing.T) {
 	if err := Validate(u); err == nil { // Complains about illegal characters
 		t.Error(err)
 	}
+	u.Username = "\u0301\u0301"
+	if err := Validate(u); err == nil || err.Error() != "Username is invalid." { // Complains about only combining marks
+		t.Error(err)
+	}
+	u.Username = "ze\u0301"
+	if err := Validate(u); err != nil && err.Error() == "Username is invalid." { // Accepts combining marks after a letter
+		t.Error(err)
+	}
+	u.Username = "zgé"
+	if err := Validate(u); err == nil || err.Error() == "Username is invalid." { // Accepts non-ASCII letters
+		t.Error(err)
+	}
 	u.Username = "zg"
 	if err := Validate(u); err == nil { // Complains about length
 		t.Error(err)
@@ -130,38 +200,95 @@ func TestCreate(t *testing.T) {
 		t.Error(err)
 	}
 	currentUserCount := len(users)
-	u := Create(User{Username: "zzg", Password: password, Address: address})
+	u := Create(context.Background(), User{Username: "zzg", Password: password, Address: address})
 	if len(GetAll()) == currentUserCount {
 		t.Error("User wasn't created.")
 	}
 	if u.Created.IsZero() {
 		t.Error("User creation time not set.")
 	}
-	if !CheckPassword(u, password) {
+	if !CheckPassword(context.Background(), u, password) {
 		t.Error("User password incorrectly set.")
 	}
 }
 
+func TestCreateAdmin(t *testing.T) {
+	if u, err := CreateAdmin(context.Background(), config.Admin{}); err != nil || u.ID != "" {
+		t.Errorf("Expected no admin, got %v, %v.", u.ID, err)
+	}
+	u, err := CreateAdmin(context.Background(), config.Admin{Username: "sysop", Email: "sysop@example.com", Password: "S3crET!@#$"})
+	if err != nil || u.Role != RoleAdmin || !CheckPassword(context.Background(), FindByID(context.Background(), u.ID), "S3crET!@#$") {
+		t.Errorf("Expected %s, got %s, %v.", RoleAdmin, u.Role, err)
+	}
+	if _, err := CreateAdmin(context.Background(), config.Admin{Username: "sysop", Email: "sysop2@example.com", Password: "S3crET!@#$"}); err != ErrUsernameTaken {
+		t.Errorf("Expected the username to be taken, got %v.", err)
+	}
+	if _, err := CreateAdmin(context.Background(), config.Admin{Username: "groot", Email: "groot@example.com", Password: "secret"}); err == nil {
+		t.Error("Expected the password to be validated.")
+	}
+
+	address, _ := mail.ParseAddress("rooted@example.com")
+	if u := Create(context.Background(), User{Username: "rooted", Password: "S3crET!@#$", Address: address}); u.Role != RoleUser {
+		t.Errorf("Expected %s, got %s.", RoleUser, u.Role)
+	}
+}
+
+func TestCreateUnique(t *testing.T) {
+	users := GetAll()
+	address, err := mail.ParseAddress("unique@example.com")
+	if err != nil {
+		t.Error(err)
+	}
+	u, err := CreateUnique(context.Background(), User{Username: "unique", Password: "S3crET!@#$", Address: address})
+	if err != nil {
+		t.Error(err)
+	}
+	if len(u.ID) == 0 {
+		t.Error("User wasn't created.")
+	}
+	if _, err := CreateUnique(context.Background(), User{Username: "UNIQUE", Password: "S3crET!@#$", Address: users[1].Address}); err != ErrUsernameTaken {
+		t.Error("Expected username to be taken.")
+	}
+	if _, err := CreateUnique(context.Background(), User{Username: "unique2", Password: "S3crET!@#$", Address: address}); err != ErrAddressTaken {
+		t.Error("Expected address to be taken.")
+	}
+	Delete(context.Background(), u.ID)
+}
+
+func TestDeactivate(t *testing.T) {
+	if u := Deactivate(context.Background(), ""); len(u.ID) != 0 {
+		t.Error("Deactivate fail should return empty user.")
+	}
+	address, err := mail.ParseAddress("deactivate@example.com")
+	if err != nil {
+		t.Error(err)
+	}
+	u := Create(context.Background(), User{Username: "deactivate", Password: "S3crET!@#$", Address: address})
+	if u = Deactivate(context.Background(), u.ID); !u.Deactivated || u.Version != 2 {
+		t.Error("User was not deactivated.")
+	}
+	Delete(context.Background(), u.ID)
+}
+
 func TestUpdate(t *testing.T) {
 	users := GetAll()
 	address, err := mail.ParseAddress("zg@zk.gd")
 	if err != nil {
 		t.Error(err)
 	}
-	u := Update(User{})
-	if len(u.ID) != 0 {
-		t.Error("Patch fail should return empty user.")
+	if _, err := Update(context.Background(), User{}); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound, got %v.", err)
 	}
-	userBeforeUpdate := FindByID(users[0].ID)
+	userBeforeUpdate := FindByID(context.Background(), users[0].ID)
 	updatedUser := User{ID: users[0].ID, Username: "zgg", Password: "S3crET!@#$", Address: address}
-	u = Update(updatedUser)
-	if len(u.ID) == 0 {
-		t.Error("User was not found.")
+	u, err := Update(context.Background(), updatedUser)
+	if err != nil {
+		t.Error(err)
 	}
 	if u.Username != updatedUser.Username {
 		t.Error("Username was not updated.")
 	}
-	if !CheckPassword(u, updatedUser.Password) {
+	if !CheckPassword(context.Background(), u, updatedUser.Password) {
 		t.Error("Password was not updated.")
 	}
 	if u.Address.Address != updatedUser.Address.Address {
@@ -178,22 +305,21 @@ func TestPatch(t *testing.T) {
 	if err != nil {
 		t.Error(err)
 	}
-	u := Patch(User{})
-	if len(u.ID) != 0 {
-		t.Error("Patch fail should return empty user.")
+	if _, err := Patch(context.Background(), User{}); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound, got %v.", err)
 	}
-	userToPatch := FindByID(users[0].ID)
+	userToPatch := FindByID(context.Background(), users[0].ID)
 	userToPatch.Username = "zzg"
 	userToPatch.Password = "S3crET!@#$"
 	userToPatch.Address = address
-	u = Patch(userToPatch)
-	if len(u.ID) == 0 {
-		t.Error("User was not found.")
+	u, err := Patch(context.Background(), userToPatch)
+	if err != nil {
+		t.Error(err)
 	}
 	if u.Username != userToPatch.Username {
 		t.Error("Username was not patched.")
 	}
-	if !CheckPassword(u, userToPatch.Password) {
+	if !CheckPassword(context.Background(), u, userToPatch.Password) {
 		t.Error("Password was not patched.")
 	}
 	if u.Address.Address != userToPatch.Address.Address {
@@ -202,21 +328,56 @@ func TestPatch(t *testing.T) {
 	if u.Updated.Sub(userToPatch.Updated) == 0 {
 		t.Error("Last Updated not patched.")
 	}
+	u, _ = Patch(context.Background(), User{ID: userToPatch.ID, Username: "zzgg"})
+	if u.Username != "zzgg" || u.Address.Address != address.Address {
+		t.Error("Patch without address should only patch username.")
+	}
+}
+
+func TestVersion(t *testing.T) {
+	address, err := mail.ParseAddress("version@example.com")
+	if err != nil {
+		t.Error(err)
+	}
+	u := Create(context.Background(), User{Username: "version", Password: "S3crET!@#$", Address: address})
+	if u.Version != 1 {
+		t.Error("Created user should be at version 1.")
+	}
+	u, _ = Patch(context.Background(), User{ID: u.ID, Username: "versioned", Version: 1})
+	if u.Version != 2 {
+		t.Error("Patched user should be at version 2.")
+	}
+	if _, err := Patch(context.Background(), User{ID: u.ID, Username: "stale", Version: 1}); err != ErrVersionMismatch {
+		t.Error("Patch of stale version should fail.")
+	}
+	if _, err := Update(context.Background(), User{ID: u.ID, Username: "stale", Version: 1}); err != ErrVersionMismatch {
+		t.Error("Update of stale version should fail.")
+	}
+	u.Password = "S3crET!@#$"
+	if u, _ = Update(context.Background(), u); u.Version != 3 {
+		t.Error("Updated user should be at version 3.")
+	}
+	if err := DeleteVersion(context.Background(), u.ID, 2); err != ErrVersionMismatch {
+		t.Error("Delete of stale version should fail.")
+	}
+	if err := DeleteVersion(context.Background(), u.ID, 3); err != nil {
+		t.Error(err)
+	}
 }
 
 func TestDelete(t *testing.T) {
 	users := GetAll()
 	u := User{}
-	err := Delete(u.ID)
+	err := Delete(context.Background(), u.ID)
 	if err.Error() != "Not found" {
 		t.Error("Delete fail should return empty user.")
 	}
-	u = FindByID(users[0].ID)
-	err = Delete(u.ID)
+	u = FindByID(context.Background(), users[0].ID)
+	err = Delete(context.Background(), u.ID)
 	if err != nil {
 		t.Error(err)
 	}
-	if users[0].ID == u.ID {
+	if FindByID(context.Background(), u.ID).ID == u.ID {
 		t.Error("User was not deleted.")
 	}
 }
@@ -224,12 +385,12 @@ func TestDelete(t *testing.T) {
 func TestMain(m *testing.M) {
 	usernames := [4]string{"alex", "blake", "corey", "devon"}
 	for _, un := range usernames {
-		addr, err := mail.ParseAddress(strings.Title(un) + "<" + un + "@example.com>")
+		addr, err := mail.ParseAddress(cases.Title(language.Und).String(un) + "<" + un + "@example.com>")
 		if err != nil {
 			log.Fatal(err)
 		}
 		u := User{Username: un, Password: "1@E4s67890", Address: addr, Created: time.Now()}
-		_ = Create(u)
+		_ = Create(context.Background(), u)
 	}
 
 	os.Exit(m.Run())